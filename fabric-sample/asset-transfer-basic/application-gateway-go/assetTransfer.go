@@ -220,23 +220,70 @@ func getHistoryForKey(contract *client.Contract) {
 	fmt.Printf("*** Result:%s\n", result)
 }
 
+// Inspection is the structured input of CreateAssetFromInspection. Every checkpoint takes one of
+// "pass", "fail", "not_applicable" or "pending"; GetInspectionSchema returns the full JSON schema.
+type Inspection struct {
+	ID                      string `json:"ID"`
+	Category                string `json:"Category,omitempty"`
+	AcceptanceSampling      string `json:"AcceptanceSampling"`
+	ManufacturingEquipment  string `json:"ManufacturingEquipment"`
+	TransportationEquipment string `json:"TransportationEquipment"`
+	InventoryManagement     string `json:"InventoryManagement"`
+	SaveEquipment           string `json:"SaveEquipment"`
+	A01                     string `json:"A01"`
+	A02                     string `json:"A02"`
+	A03                     string `json:"A03"`
+	A04                     string `json:"A04"`
+	B01                     string `json:"B01"`
+	C01                     string `json:"C01"`
+}
+
 // Submit a transaction synchronously, blocking until it has been committed to the ledger.
 func createAsset(contract *client.Contract) {
-	fmt.Printf("\n--> Submit Transaction: CreateAsset, creates new asset with ID, Color, Size, Owner and AppraisedValue arguments \n")
-	// id string, acceptancesample string, manufacturingequipment string, transportationequipment string, inventorymanagement string, saveequipment string, a01 string, a02 string, a03 string, a04 string, b01 string, c01 string
-	// _, err := contract.SubmitTransaction("CreateAsset", id, acceptancesample, manufacturingequipment, transportationequipment, inventorymanagement, saveequipment, a01, a02, a03, a04, b01, c01)
-	//0 1 1 0 1 1 1 1 1 1 0
-	//0 1 1 0 1 0 0 0 1 1 0
-	_, err := contract.SubmitTransaction("CreateAsset", "asset1", "0", "1", "1", "0", "1", "0", "0", "0", "1", "1", "0")
+	fmt.Printf("\n--> Submit Transaction: CreateAssetFromInspection, creates new asset from named checkpoint results \n")
+
+	submitInspection(contract, Inspection{
+		ID:                      "asset1",
+		AcceptanceSampling:      "fail",
+		ManufacturingEquipment:  "pass",
+		TransportationEquipment: "pass",
+		InventoryManagement:     "fail",
+		SaveEquipment:           "pass",
+		A01:                     "fail",
+		A02:                     "fail",
+		A03:                     "fail",
+		A04:                     "pass",
+		B01:                     "pass",
+		C01:                     "fail",
+	})
+}
+
+func createAssetSec(contract *client.Contract) {
+	fmt.Printf("\n--> Submit Transaction: CreateAssetFromInspection, creates a second asset whose C01 check does not apply \n")
+
+	submitInspection(contract, Inspection{
+		ID:                      "asset2",
+		AcceptanceSampling:      "fail",
+		ManufacturingEquipment:  "pass",
+		TransportationEquipment: "pass",
+		InventoryManagement:     "fail",
+		SaveEquipment:           "pass",
+		A01:                     "pass",
+		A02:                     "pass",
+		A03:                     "pass",
+		A04:                     "pass",
+		B01:                     "pass",
+		C01:                     "not_applicable",
+	})
+}
+
+func submitInspection(contract *client.Contract, inspection Inspection) {
+	inspectionJSON, err := json.Marshal(inspection)
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+		panic(err)
 	}
 
-	fmt.Printf("*** Transaction committed successfully\n")
-}
-func createAssetSec(contract *client.Contract) {
-	fmt.Printf("\n--> Submit Transaction: CreateAssetSec, creates new asset with ID, Color, Size, Owner and AppraisedValue arguments \n")
-	_, err := contract.SubmitTransaction("CreateAsset", "asset2", "0", "1", "1", "0", "1", "1", "1", "1", "1", "1", "0")
+	_, err = contract.SubmitTransaction("CreateAssetFromInspection", string(inspectionJSON))
 	if err != nil {
 		panic(fmt.Errorf("failed to submit transaction: %w", err))
 	}
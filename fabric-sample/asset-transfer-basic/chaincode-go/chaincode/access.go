@@ -0,0 +1,133 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// participantPattern matches the organization number at the start of a participant name.
+var participantPattern = regexp.MustCompile(`^org([1-9][0-9]*)`)
+
+// GetContributions returns the checkpoint results scored against a participant. Only the participant
+// itself and the consortium admins may read them.
+func (s *SmartContract) GetContributions(ctx contractapi.TransactionContextInterface, participant string) ([]*Contribution, error) {
+	full, err := s.canViewCredit(ctx, participant)
+	if err != nil {
+		return nil, err
+	}
+	if !full {
+		return nil, fmt.Errorf("only %s and the consortium admins can read its contributions", participant)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(contributionObjectType, []string{participant})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*Contribution
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var contribution Contribution
+		err = json.Unmarshal(queryResponse.Value, &contribution)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &contribution)
+	}
+
+	return result, nil
+}
+
+// canViewCredit reports whether the caller may see the full credit record of a participant.
+func (s *SmartContract) canViewCredit(ctx contractapi.TransactionContextInterface, participant string) (bool, error) {
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the caller's MSPID: %v", err)
+	}
+	if caller == participant && mspID == participantMSPID(participant) {
+		return true, nil
+	}
+
+	return s.isConsortiumAdmin(ctx, caller)
+}
+
+// isConsortiumAdmin reports whether the caller is org1admin of Org1MSP or, once the consortium governs
+// the scoring policy, an admin (NodeOU "admin") of a member organization.
+func (s *SmartContract) isConsortiumAdmin(ctx contractapi.TransactionContextInterface, caller string) (bool, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the caller's MSPID: %v", err)
+	}
+	if caller == "org1admin" && mspID == participantMSPID(caller) {
+		return true, nil
+	}
+
+	governance, err := s.readGovernance(ctx)
+	if err != nil {
+		return false, err
+	}
+	if governance == nil {
+		return false, nil
+	}
+	if !isMember(governance, mspID) {
+		return false, nil
+	}
+
+	return hasAdminRole(ctx)
+}
+
+// participantMSPID returns the MSPID of the organization a participant belongs to. Participants are
+// named after their organization, as org5admin1 belongs to Org5MSP; other names belong to none.
+func participantMSPID(participant string) string {
+	match := participantPattern.FindStringSubmatch(participant)
+	if match == nil {
+		return ""
+	}
+
+	return "Org" + match[1] + "MSP"
+}
+
+// hasAdminRole reports whether the caller's certificate has the admin NodeOU.
+func hasAdminRole(ctx contractapi.TransactionContextInterface) (bool, error) {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the caller's certificate: %v", err)
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if ou == "admin" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// grade maps the final score of a credit record to a letter grade.
+func grade(credit *Credit) string {
+	switch {
+	case credit.Transaction == 0:
+		return "unrated"
+	case credit.FinalScore >= 0.9:
+		return "A"
+	case credit.FinalScore >= 0.75:
+		return "B"
+	case credit.FinalScore >= 0.5:
+		return "C"
+	default:
+		return "D"
+	}
+}
@@ -0,0 +1,68 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreditAccess(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+	commit := setState(chaincodeStub, map[string][]byte{})
+
+	assetTransfer := chaincode.SmartContract{}
+	for _, id := range []string{"org1admin", "org2admin", "org5admin", "org5admin1"} {
+		err := assetTransfer.RegisterParticipant(transactionContext, id)
+		commit()
+		require.NoError(t, err)
+	}
+	err := assetTransfer.CreateAsset(transactionContext, "asset1", 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetGovernance(transactionContext, []string{"Org1MSP", "Org5MSP"}, 0.5, 0.5)
+	commit()
+	require.NoError(t, err)
+
+	setCallerWithMSP(t, chaincodeStub, "org5admin", "Org5MSP")
+	own, err := assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{ID: "org5admin", Transaction: 1, Score: 0, FinalScore: 0, Grade: "D"}, own)
+	contributions, err := assetTransfer.GetContributions(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Len(t, contributions, 1)
+	require.Equal(t, "B01", contributions[0].Checkpoint)
+
+	other, err := assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{ID: "org2admin", Grade: "A"}, other)
+	_, err = assetTransfer.GetContributions(transactionContext, "org2admin")
+	require.EqualError(t, err, "only org2admin and the consortium admins can read its contributions")
+
+	setCallerWithMSP(t, chaincodeStub, "org5admin", "Org5MSP", "admin")
+	other, err = assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{ID: "org2admin", Transaction: 1, Score: 1, FinalScore: 1, Grade: "A"}, other)
+
+	setCallerWithMSP(t, chaincodeStub, "org4admin", "Org4MSP", "admin")
+	other, err = assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{ID: "org2admin", Grade: "A"}, other, "admins of non-member organizations only see the grade")
+
+	setCallerWithMSP(t, chaincodeStub, "org5admin", "Org4MSP")
+	other, err = assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{ID: "org5admin", Grade: "D"}, other, "a participant's name from another organization does not grant access")
+	_, err = assetTransfer.GetContributions(transactionContext, "org5admin")
+	require.EqualError(t, err, "only org5admin and the consortium admins can read its contributions")
+
+	setCallerWithMSP(t, chaincodeStub, "org1admin", "Org4MSP")
+	other, err = assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{ID: "org2admin", Grade: "A"}, other, "org1admin of another organization is not a consortium admin")
+}
@@ -0,0 +1,288 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const penaltyObjectType = "penalty"
+
+// resultNotApplicable is the result recorded for a checkpoint that does not apply to an asset
+const resultNotApplicable = -1
+
+// resultPending is the result recorded for a stage of an asset that has not been completed yet
+const resultPending = -2
+
+// Penalty overrides the default number of percentage points deducted when a checkpoint fails
+type Penalty struct {
+	Checkpoint string  `json:"Checkpoint"`
+	Penalty    float32 `json:"Penalty"`
+}
+
+// checkpoint describes an inspection item of an asset, the participant it is scored against and the
+// percentage points deducted from that participant's score when it fails. An empty participant means
+// the checkpoint is scored against the source of the asset.
+type checkpoint struct {
+	Name        string
+	Participant string
+	Penalty     float32
+}
+
+var checkpoints = []checkpoint{
+	{Name: "AcceptanceSampling", Penalty: 20},
+	{Name: "ManufacturingEquipment", Penalty: 20},
+	{Name: "TransportationEquipment", Penalty: 20},
+	{Name: "InventoryManagement", Penalty: 20},
+	{Name: "SaveEquipment", Penalty: 20},
+	{Name: "A01", Participant: "org2admin", Penalty: 25},
+	{Name: "A02", Participant: "org2admin", Penalty: 25},
+	{Name: "A03", Participant: "org2admin", Penalty: 25},
+	{Name: "A04", Participant: "org2admin", Penalty: 25},
+	{Name: "B01", Participant: "org5admin", Penalty: 100},
+	{Name: "C01", Participant: "org5admin1", Penalty: 100},
+}
+
+// sourceCheckpoints are the checkpoints the source of an asset reports about itself
+var sourceCheckpoints = []string{"AcceptanceSampling", "ManufacturingEquipment", "TransportationEquipment", "InventoryManagement", "SaveEquipment"}
+
+// checkpointNames returns the names of all checkpoints.
+func checkpointNames() []string {
+	var names []string
+	for _, c := range checkpoints {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// findCheckpoint returns the checkpoint with the given name.
+func findCheckpoint(name string) (checkpoint, error) {
+	for _, c := range checkpoints {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return checkpoint{}, fmt.Errorf("the checkpoint %s does not exist", name)
+}
+
+// SetCheckpointPenalty sets the number of percentage points deducted when a checkpoint fails.
+func (s *SmartContract) SetCheckpointPenalty(ctx contractapi.TransactionContextInterface, checkpointName string, penalty float32) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putCheckpointPenalty(ctx, checkpointName, penalty)
+}
+
+// validateCheckpointPenalty checks the penalty of a checkpoint before it is stored.
+func validateCheckpointPenalty(checkpointName string, penalty float32) error {
+	_, err := findCheckpoint(checkpointName)
+	if err != nil {
+		return err
+	}
+
+	if penalty < 0 {
+		return fmt.Errorf("the penalty must not be negative")
+	}
+
+	return nil
+}
+
+// putCheckpointPenalty stores the penalty of a checkpoint.
+func (s *SmartContract) putCheckpointPenalty(ctx contractapi.TransactionContextInterface, checkpointName string, penalty float32) error {
+	err := validateCheckpointPenalty(checkpointName, penalty)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(penaltyObjectType, []string{checkpointName})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	penaltyJSON, err := json.Marshal(Penalty{Checkpoint: checkpointName, Penalty: penalty})
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, penaltyJSON)
+}
+
+// checkpointPenalty returns the penalty of a checkpoint, which is its default unless overridden.
+func (s *SmartContract) checkpointPenalty(ctx contractapi.TransactionContextInterface, c checkpoint) (float32, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(penaltyObjectType, []string{c.Name})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	penaltyJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if penaltyJSON == nil {
+		return c.Penalty, nil
+	}
+
+	var penalty Penalty
+	err = json.Unmarshal(penaltyJSON, &penalty)
+	if err != nil {
+		return 0, err
+	}
+
+	return penalty.Penalty, nil
+}
+
+// participant returns the participant the checkpoint is scored against for the given asset.
+func (c checkpoint) participant(asset *Asset) string {
+	if c.Participant != "" {
+		return c.Participant
+	}
+	if asset.Source != "" {
+		return asset.Source
+	}
+	return asset.Owner
+}
+
+// checkpointResult returns the result recorded on the asset for the named checkpoint.
+func checkpointResult(asset *Asset, name string) int {
+	switch name {
+	case "AcceptanceSampling":
+		return asset.AcceptanceSampling
+	case "ManufacturingEquipment":
+		return asset.ManufacturingEquipment
+	case "TransportationEquipment":
+		return asset.TransportationEquipment
+	case "InventoryManagement":
+		return asset.InventoryManagement
+	case "SaveEquipment":
+		return asset.SaveEquipment
+	case "A01":
+		return asset.A01
+	case "A02":
+		return asset.A02
+	case "A03":
+		return asset.A03
+	case "A04":
+		return asset.A04
+	case "B01":
+		return asset.B01
+	case "C01":
+		return asset.C01
+	}
+	return 0
+}
+
+// setCheckpointResult records the result of the named checkpoint on the asset.
+func setCheckpointResult(asset *Asset, name string, result int) {
+	switch name {
+	case "AcceptanceSampling":
+		asset.AcceptanceSampling = result
+	case "ManufacturingEquipment":
+		asset.ManufacturingEquipment = result
+	case "TransportationEquipment":
+		asset.TransportationEquipment = result
+	case "InventoryManagement":
+		asset.InventoryManagement = result
+	case "SaveEquipment":
+		asset.SaveEquipment = result
+	case "A01":
+		asset.A01 = result
+	case "A02":
+		asset.A02 = result
+	case "A03":
+		asset.A03 = result
+	case "A04":
+		asset.A04 = result
+	case "B01":
+		asset.B01 = result
+	case "C01":
+		asset.C01 = result
+	}
+}
+
+// notApplicable reports whether the named checkpoint does not apply to the asset.
+func notApplicable(asset *Asset, name string) bool {
+	return checkpointResult(asset, name) == resultNotApplicable
+}
+
+// pending reports whether the named checkpoint of the asset is a stage that has not been completed yet.
+func pending(asset *Asset, name string) bool {
+	return checkpointResult(asset, name) == resultPending
+}
+
+// setCheckedAt records when the named checkpoint of the asset was last checked.
+func setCheckedAt(asset *Asset, name string, timestamp time.Time) {
+	if asset.CheckedAt == nil {
+		asset.CheckedAt = make(map[string]time.Time)
+	}
+	asset.CheckedAt[name] = timestamp
+}
+
+// scoreCheckpoints applies the results of the named checkpoints of an asset to the credit of the
+// participants they are scored against, one credit transaction per participant, and records a
+// contribution for each checkpoint. Checkpoints that do not apply to the asset or are still pending are
+// skipped, as are checkpoints that require dual inspection; those are scored once their inspection has
+// been decided.
+func (s *SmartContract) scoreCheckpoints(ctx contractapi.TransactionContextInterface, rater string, asset *Asset, names []string) error {
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	var participants []string
+	scores := make(map[string]float32)
+	contributions := make(map[string][]Contribution)
+
+	for _, name := range names {
+		c, err := findCheckpoint(name)
+		if err != nil {
+			return err
+		}
+
+		if notApplicable(asset, name) || pending(asset, name) {
+			continue
+		}
+
+		dual, err := s.readDualInspection(ctx, name)
+		if err != nil {
+			return err
+		}
+		if dual != nil {
+			continue
+		}
+
+		participant := c.participant(asset)
+		if _, ok := scores[participant]; !ok {
+			participants = append(participants, participant)
+			scores[participant] = 1
+		}
+
+		contribution := Contribution{Participant: participant, Checkpoint: name, Passed: checkpointResult(asset, name) != 0}
+		if !contribution.Passed {
+			err = s.applyPenalty(ctx, c, timestamp, &contribution)
+			if err != nil {
+				return err
+			}
+			scores[participant] = (scores[participant]*100 - contribution.Penalty) / 100
+		}
+		contributions[participant] = append(contributions[participant], contribution)
+		setCheckedAt(asset, name, timestamp)
+	}
+
+	for _, participant := range participants {
+		err := s.updateCredit(ctx, participant, scores[participant], asset.ID)
+		if err != nil {
+			return err
+		}
+
+		err = s.putContributions(ctx, rater, asset.ID, timestamp, contributions[participant])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
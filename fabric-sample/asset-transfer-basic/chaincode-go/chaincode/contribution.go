@@ -0,0 +1,83 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const contributionObjectType = "contribution"
+
+// Contribution records the result of a single checkpoint evaluation and the participant it was scored against.
+// Penalty is the number of percentage points deducted for a failure, after any escalation.
+type Contribution struct {
+	Participant string      `json:"Participant"`
+	Rater       string      `json:"Rater"`
+	AssetID     string      `json:"AssetID"`
+	Checkpoint  string      `json:"Checkpoint"`
+	Passed      bool        `json:"Passed"`
+	Penalty     float32     `json:"Penalty"`
+	Escalation  *Escalation `json:"Escalation,omitempty" metadata:",optional"`
+	TxID        string      `json:"TxID"`
+	TimeStamp   time.Time   `json:"TimeStamp"`
+}
+
+// putContributions stores the checkpoint results the rater reported for an asset under composite keys
+// participant~asset~checkpoint~txid, so that they can be range queried per participant, protects them
+// with the scoring organizations' endorsement and updates the rater's trust in every other participant.
+func (s *SmartContract) putContributions(ctx contractapi.TransactionContextInterface, rater string, assetID string, timestamp time.Time, contributions []Contribution) error {
+	txID := ctx.GetStub().GetTxID()
+
+	// GetState does not return the transaction's own writes, so the trust in each ratee is written once
+	var ratees []string
+	passes := make(map[string]int)
+	checks := make(map[string]int)
+
+	for _, contribution := range contributions {
+		contribution.Rater = rater
+		contribution.AssetID = assetID
+		contribution.TxID = txID
+		contribution.TimeStamp = timestamp
+
+		key, err := ctx.GetStub().CreateCompositeKey(contributionObjectType, []string{contribution.Participant, assetID, contribution.Checkpoint, txID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+
+		contributionJSON, err := json.Marshal(contribution)
+		if err != nil {
+			return err
+		}
+
+		err = ctx.GetStub().PutState(key, contributionJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put to world state. %v", err)
+		}
+
+		err = s.protectKey(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if contribution.Participant != rater {
+			if _, ok := checks[contribution.Participant]; !ok {
+				ratees = append(ratees, contribution.Participant)
+			}
+			checks[contribution.Participant]++
+			if contribution.Passed {
+				passes[contribution.Participant]++
+			}
+		}
+	}
+
+	for _, ratee := range ratees {
+		err := s.updateTrust(ctx, rater, ratee, passes[ratee], checks[ratee])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const custodyObjectType = "custody"
+
+// custodyKeyTimeFormat is a fixed width timestamp so that custody keys sort in the order the events were
+// recorded; RFC 3339 with nanoseconds drops trailing zeros and sorts "00.5Z" before "00Z".
+const custodyKeyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// CustodyEvent is one entry of the custody chain of an asset. Type is "handover" when the custodian
+// passes the asset to To, "location" when it reports where the asset is, or "seal" when it replaces
+// the seal. A handover's carrier is the logistics partner accountable for the following leg.
+type CustodyEvent struct {
+	AssetID   string    `json:"AssetID"`
+	Type      string    `json:"Type"`
+	From      string    `json:"From"`
+	To        string    `json:"To"`
+	Location  string    `json:"Location"`
+	Carrier   string    `json:"Carrier"`
+	SealID    string    `json:"SealID"`
+	TxID      string    `json:"TxID"`
+	TimeStamp time.Time `json:"TimeStamp"`
+}
+
+// RecordCustodyEvent appends an event to the custody chain of an asset. The caller must be the
+// current custodian, which is the asset's owner until the first handover. The seal must match the
+// previous event's seal unless the event replaces it.
+func (s *SmartContract) RecordCustodyEvent(ctx contractapi.TransactionContextInterface, assetID string, eventType string, to string, location string, carrier string, sealID string) error {
+	if eventType != "handover" && eventType != "location" && eventType != "seal" {
+		return fmt.Errorf("unsupported custody event %q, expected handover, location or seal", eventType)
+	}
+	if eventType == "handover" && to == "" {
+		return fmt.Errorf("a handover must name the receiving custodian")
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	chain, err := s.GetCustodyChain(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	custodian := asset.Owner
+	var seal string
+	for _, event := range chain {
+		if event.Type == "handover" {
+			custodian = event.To
+		}
+		if event.SealID != "" {
+			seal = event.SealID
+		}
+	}
+
+	if caller != custodian {
+		return fmt.Errorf("the custody chain of asset %s is held by %s, not %s", assetID, custodian, caller)
+	}
+	if eventType != "seal" && seal != "" && sealID != seal {
+		return fmt.Errorf("the seal %s of asset %s does not match the recorded seal %s", sealID, assetID, seal)
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	event := CustodyEvent{
+		AssetID:   assetID,
+		Type:      eventType,
+		From:      caller,
+		To:        to,
+		Location:  location,
+		Carrier:   carrier,
+		SealID:    sealID,
+		TxID:      ctx.GetStub().GetTxID(),
+		TimeStamp: timestamp,
+	}
+	if eventType != "handover" {
+		event.To = caller
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(custodyObjectType, []string{assetID, timestamp.UTC().Format(custodyKeyTimeFormat), event.TxID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, eventJSON)
+}
+
+// GetCustodyChain returns the custody events of an asset in the order they were recorded.
+func (s *SmartContract) GetCustodyChain(ctx contractapi.TransactionContextInterface, assetID string) ([]*CustodyEvent, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(custodyObjectType, []string{assetID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*CustodyEvent
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var event CustodyEvent
+		err = json.Unmarshal(queryResponse.Value, &event)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &event)
+	}
+
+	return result, nil
+}
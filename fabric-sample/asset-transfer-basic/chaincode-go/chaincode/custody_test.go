@@ -0,0 +1,68 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCustodyChain(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	asset, err := json.Marshal(&chaincode.Asset{ID: "asset1", Owner: "org1admin"})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"asset1": asset})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.RecordCustodyEvent(transactionContext, "asset1", "handover", "org5admin", "Taichung", "truck-7", "S100")
+	commit()
+	require.NoError(t, err)
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 16, 0, 0, 0, 0, time.UTC)), nil)
+	err = assetTransfer.RecordCustodyEvent(transactionContext, "asset1", "location", "", "Taipei", "", "S100")
+	commit()
+	require.EqualError(t, err, "the custody chain of asset asset1 is held by org5admin, not org1admin")
+
+	setCaller(t, chaincodeStub, "org5admin")
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 16, 0, 0, 0, 0, time.UTC)), nil)
+	err = assetTransfer.RecordCustodyEvent(transactionContext, "asset1", "location", "", "Taipei", "", "S999")
+	commit()
+	require.EqualError(t, err, "the seal S999 of asset asset1 does not match the recorded seal S100")
+	err = assetTransfer.RecordCustodyEvent(transactionContext, "asset1", "location", "", "Taipei", "", "S100")
+	commit()
+	require.NoError(t, err)
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 17, 0, 0, 0, 0, time.UTC)), nil)
+	err = assetTransfer.RecordCustodyEvent(transactionContext, "asset1", "handover", "org2admin", "Taipei", "", "S100")
+	commit()
+	require.NoError(t, err)
+
+	chain, err := assetTransfer.GetCustodyChain(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Len(t, chain, 3)
+	require.Equal(t, []string{"org5admin", "org5admin", "org2admin"}, []string{chain[0].To, chain[1].To, chain[2].To})
+	require.Equal(t, "truck-7", chain[0].Carrier)
+
+	setCaller(t, chaincodeStub, "org2admin")
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 17, 0, 0, 0, 500000000, time.UTC)), nil)
+	err = assetTransfer.RecordCustodyEvent(transactionContext, "asset1", "location", "", "Kaohsiung", "", "S100")
+	commit()
+	require.NoError(t, err)
+	chain, err = assetTransfer.GetCustodyChain(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Len(t, chain, 4)
+	require.Equal(t, "Kaohsiung", chain[3].Location, "events within the same second keep their order")
+
+	err = assetTransfer.RecordCustodyEvent(transactionContext, "asset1", "teleport", "", "", "", "")
+	commit()
+	require.EqualError(t, err, `unsupported custody event "teleport", expected handover, location or seal`)
+}
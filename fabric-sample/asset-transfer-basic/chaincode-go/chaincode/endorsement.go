@@ -0,0 +1,179 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const scoringOrgsObjectType = "scoringOrgs"
+
+// SetScoringOrgs sets the organizations whose peers must all endorse changes to credit records and
+// contributions. It applies to participants registered afterwards; ProtectCredit applies it to
+// existing ones. An empty list stops protecting new records.
+func (s *SmartContract) SetScoringOrgs(ctx contractapi.TransactionContextInterface, mspIDs []string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putScoringOrgs(ctx, mspIDs)
+}
+
+// ReadScoringOrgs returns the organizations that must endorse changes to credit records.
+func (s *SmartContract) ReadScoringOrgs(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(scoringOrgsObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	orgsJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if orgsJSON == nil {
+		return nil, nil
+	}
+
+	var mspIDs []string
+	err = json.Unmarshal(orgsJSON, &mspIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return mspIDs, nil
+}
+
+// ProtectCredit applies the current scoring organizations to the credit record of an existing participant.
+func (s *SmartContract) ProtectCredit(ctx contractapi.TransactionContextInterface, id string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.readCredit(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	mspIDs, err := s.ReadScoringOrgs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(mspIDs) == 0 {
+		return fmt.Errorf("no scoring organizations are set")
+	}
+
+	return setStateBasedEndorsement(ctx, id, mspIDs)
+}
+
+// GetCreditEndorsement returns the organizations whose peers must endorse changes to a participant's credit record.
+func (s *SmartContract) GetCreditEndorsement(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
+	policy, err := ctx.GetStub().GetStateValidationParameter(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validation parameter: %v", err)
+	}
+	if len(policy) == 0 {
+		return []string{}, nil
+	}
+
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return endorsementPolicy.ListOrgs(), nil
+}
+
+// protectKey applies the scoring organizations, if any are set, to a credit or contribution key.
+func (s *SmartContract) protectKey(ctx contractapi.TransactionContextInterface, key string) error {
+	mspIDs, err := s.ReadScoringOrgs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(mspIDs) == 0 {
+		return nil
+	}
+
+	return setStateBasedEndorsement(ctx, key, mspIDs)
+}
+
+// checkScoringAdmin allows an admin (NodeOU "admin") of a scoring organization to adjust credit
+// directly, and otherwise falls back to checkPolicyAdmin.
+func (s *SmartContract) checkScoringAdmin(ctx contractapi.TransactionContextInterface) error {
+	mspIDs, err := s.ReadScoringOrgs(ctx)
+	if err != nil {
+		return err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get the caller's MSPID: %v", err)
+	}
+
+	for _, scoringOrg := range mspIDs {
+		if scoringOrg != mspID {
+			continue
+		}
+
+		cert, err := ctx.GetClientIdentity().GetX509Certificate()
+		if err != nil {
+			return fmt.Errorf("failed to get the caller's certificate: %v", err)
+		}
+		for _, ou := range cert.Subject.OrganizationalUnit {
+			if ou == "admin" {
+				return nil
+			}
+		}
+	}
+
+	err = s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return fmt.Errorf("only admins of the scoring organizations can update credit: %v", err)
+	}
+
+	return nil
+}
+
+// putScoringOrgs stores the scoring organizations.
+func (s *SmartContract) putScoringOrgs(ctx contractapi.TransactionContextInterface, mspIDs []string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(scoringOrgsObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if len(mspIDs) == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+
+	orgsJSON, err := json.Marshal(mspIDs)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, orgsJSON)
+}
+
+// setStateBasedEndorsement requires the peers of all given organizations to endorse changes to a key.
+func setStateBasedEndorsement(ctx contractapi.TransactionContextInterface, key string, orgsToEndorse []string) error {
+	endorsementPolicy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, orgsToEndorse...)
+	if err != nil {
+		return fmt.Errorf("failed to add org to endorsement policy: %v", err)
+	}
+	policy, err := endorsementPolicy.Policy()
+	if err != nil {
+		return fmt.Errorf("failed to create endorsement policy bytes from org: %v", err)
+	}
+	err = ctx.GetStub().SetStateValidationParameter(key, policy)
+	if err != nil {
+		return fmt.Errorf("failed to set validation parameter on %s: %v", key, err)
+	}
+
+	return nil
+}
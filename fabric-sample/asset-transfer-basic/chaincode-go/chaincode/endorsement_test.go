@@ -0,0 +1,122 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreditEndorsement(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{ID: "org1admin"})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit})
+
+	policies := map[string][]byte{}
+	chaincodeStub.SetStateValidationParameterCalls(func(key string, policy []byte) error {
+		policies[key] = policy
+		return nil
+	})
+	chaincodeStub.GetStateValidationParameterCalls(func(key string) ([]byte, error) {
+		return policies[key], nil
+	})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.ProtectCredit(transactionContext, "org1admin")
+	commit()
+	require.EqualError(t, err, "no scoring organizations are set")
+
+	err = assetTransfer.RegisterParticipant(transactionContext, "org2admin")
+	commit()
+	require.NoError(t, err)
+	require.Empty(t, policies, "records are not protected until scoring organizations are set")
+
+	err = assetTransfer.SetScoringOrgs(transactionContext, []string{"Org1MSP", "Org2MSP", "Org5MSP"})
+	commit()
+	require.NoError(t, err)
+	for _, id := range []string{"org5admin", "org5admin1"} {
+		err = assetTransfer.RegisterParticipant(transactionContext, id)
+		commit()
+		require.NoError(t, err)
+	}
+
+	orgs, err := assetTransfer.GetCreditEndorsement(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"Org1MSP", "Org2MSP", "Org5MSP"}, orgs)
+	orgs, err = assetTransfer.GetCreditEndorsement(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Empty(t, orgs)
+
+	err = assetTransfer.ProtectCredit(transactionContext, "org1admin")
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.ProtectCredit(transactionContext, "org2admin")
+	commit()
+	require.NoError(t, err)
+	orgs, err = assetTransfer.GetCreditEndorsement(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Len(t, orgs, 3)
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1)
+	commit()
+	require.NoError(t, err)
+	contributions := 0
+	for key := range policies {
+		if strings.HasPrefix(key, "\x00contribution\x00") {
+			contributions++
+		}
+	}
+	require.Equal(t, 11, contributions, "every contribution row is protected")
+
+	setCaller(t, chaincodeStub, "org2admin")
+	err = assetTransfer.ProtectCredit(transactionContext, "org2admin")
+	commit()
+	require.EqualError(t, err, "only admin from org1 can use this function")
+}
+
+func TestUpdateCreditAccess(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{ID: "org2admin"})
+	require.NoError(t, err)
+	state := map[string][]byte{"org2admin": credit}
+	commit := setState(chaincodeStub, state)
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetScoringOrgs(transactionContext, []string{"Org1MSP", "Org5MSP"})
+	commit()
+	require.NoError(t, err)
+
+	setCallerWithMSP(t, chaincodeStub, "org2admin", "Org2MSP", "admin")
+	err = assetTransfer.UpdateCredit(transactionContext, "org2admin", 1)
+	commit()
+	require.EqualError(t, err, "only admins of the scoring organizations can update credit: only admin from org1 can use this function")
+
+	setCallerWithMSP(t, chaincodeStub, "org5client", "Org5MSP", "client")
+	err = assetTransfer.UpdateCredit(transactionContext, "org2admin", 1)
+	commit()
+	require.Error(t, err, "only admins of a scoring organization may adjust credit")
+
+	setCallerWithMSP(t, chaincodeStub, "org5admin", "Org5MSP", "admin")
+	err = assetTransfer.UpdateCredit(transactionContext, "org2admin", 1)
+	commit()
+	require.NoError(t, err)
+
+	var updated chaincode.Credit
+	require.NoError(t, json.Unmarshal(state["org2admin"], &updated))
+	require.Equal(t, float32(1), updated.Score)
+	require.Equal(t, float32(1), updated.Transaction)
+}
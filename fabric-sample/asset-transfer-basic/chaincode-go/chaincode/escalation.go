@@ -0,0 +1,187 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const escalationObjectType = "escalation"
+
+// EscalationRule multiplies the penalty of a checkpoint from the given failure of the same
+// participant within the window onwards, e.g. the third B01 failure within 90 days costs twice as much
+type EscalationRule struct {
+	Checkpoint string  `json:"Checkpoint"`
+	Failures   int     `json:"Failures"`
+	WindowDays int     `json:"WindowDays"`
+	Multiplier float32 `json:"Multiplier"`
+}
+
+// Escalation explains the escalation rule applied to a failure, and which failure within the window it was
+type Escalation struct {
+	Rule       EscalationRule `json:"Rule"`
+	Occurrence int            `json:"Occurrence"`
+}
+
+// SetEscalationRule sets the penalty multiplier for the given failure of a checkpoint within a window of days.
+// A multiplier of 0 removes the rule.
+func (s *SmartContract) SetEscalationRule(ctx contractapi.TransactionContextInterface, checkpointName string, failures int, windowDays int, multiplier float32) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putEscalationRule(ctx, checkpointName, failures, windowDays, multiplier)
+}
+
+// GetEscalationRules returns the escalation rules of a checkpoint.
+func (s *SmartContract) GetEscalationRules(ctx contractapi.TransactionContextInterface, checkpointName string) ([]*EscalationRule, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(escalationObjectType, []string{checkpointName})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*EscalationRule
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var rule EscalationRule
+		err = json.Unmarshal(queryResponse.Value, &rule)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &rule)
+	}
+
+	return result, nil
+}
+
+// applyPenalty sets the penalty of a failed checkpoint on the contribution, escalated by the
+// strictest rule the participant's recent failures of the same checkpoint trigger.
+func (s *SmartContract) applyPenalty(ctx contractapi.TransactionContextInterface, c checkpoint, timestamp time.Time, contribution *Contribution) error {
+	penalty, err := s.checkpointPenalty(ctx, c)
+	if err != nil {
+		return err
+	}
+	contribution.Penalty = penalty
+
+	rules, err := s.GetEscalationRules(ctx, c.Name)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	failures, err := s.failureTimes(ctx, contribution.Participant, c.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		since := timestamp.AddDate(0, 0, -rule.WindowDays)
+		occurrence := 1
+		for _, failure := range failures {
+			if failure.After(since) {
+				occurrence++
+			}
+		}
+
+		if occurrence < rule.Failures {
+			continue
+		}
+		if contribution.Escalation != nil && contribution.Escalation.Rule.Failures > rule.Failures {
+			continue
+		}
+		contribution.Penalty = penalty * rule.Multiplier
+		contribution.Escalation = &Escalation{Rule: *rule, Occurrence: occurrence}
+	}
+
+	return nil
+}
+
+// failureTimes returns when the participant previously failed the named checkpoint.
+func (s *SmartContract) failureTimes(ctx contractapi.TransactionContextInterface, participant string, checkpointName string) ([]time.Time, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(contributionObjectType, []string{participant})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []time.Time
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var contribution Contribution
+		err = json.Unmarshal(queryResponse.Value, &contribution)
+		if err != nil {
+			return nil, err
+		}
+
+		if contribution.Checkpoint == checkpointName && !contribution.Passed {
+			result = append(result, contribution.TimeStamp)
+		}
+	}
+
+	return result, nil
+}
+
+// validateEscalationRule checks an escalation rule of a checkpoint before it is stored.
+func validateEscalationRule(checkpointName string, failures int, windowDays int, multiplier float32) error {
+	_, err := findCheckpoint(checkpointName)
+	if err != nil {
+		return err
+	}
+
+	if failures < 2 {
+		return fmt.Errorf("an escalation rule must start from the second failure or later")
+	}
+	if windowDays <= 0 {
+		return fmt.Errorf("the window of an escalation rule must be at least one day")
+	}
+	if multiplier < 0 {
+		return fmt.Errorf("the multiplier must not be negative")
+	}
+
+	return nil
+}
+
+// putEscalationRule stores an escalation rule of a checkpoint.
+func (s *SmartContract) putEscalationRule(ctx contractapi.TransactionContextInterface, checkpointName string, failures int, windowDays int, multiplier float32) error {
+	err := validateEscalationRule(checkpointName, failures, windowDays, multiplier)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(escalationObjectType, []string{checkpointName, fmt.Sprintf("%04d", failures)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if multiplier == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+
+	rule := EscalationRule{
+		Checkpoint: checkpointName,
+		Failures:   failures,
+		WindowDays: windowDays,
+		Multiplier: multiplier,
+	}
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, ruleJSON)
+}
@@ -0,0 +1,58 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscalation(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	state := map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit}
+	commit := setState(chaincodeStub, state)
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetEscalationRule(transactionContext, "B01", 3, 90, 2)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetEscalationRule(transactionContext, "B01", 1, 90, 2)
+	commit()
+	require.EqualError(t, err, "an escalation rule must start from the second failure or later")
+
+	for _, id := range []string{"asset1", "asset2", "asset3"} {
+		err = assetTransfer.CreateAsset(transactionContext, id, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1)
+		commit()
+		require.NoError(t, err)
+	}
+
+	org5, err := assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(-1), org5.Score, "the third failure costs twice as much")
+
+	key, err := shim.CreateCompositeKey("contribution", []string{"org5admin", "asset3", "B01", ""})
+	require.NoError(t, err)
+	var contribution chaincode.Contribution
+	err = json.Unmarshal(state[key], &contribution)
+	require.NoError(t, err)
+	require.Equal(t, float32(200), contribution.Penalty)
+	require.Equal(t, &chaincode.Escalation{Rule: chaincode.EscalationRule{Checkpoint: "B01", Failures: 3, WindowDays: 90, Multiplier: 2}, Occurrence: 3}, contribution.Escalation)
+
+	key, err = shim.CreateCompositeKey("contribution", []string{"org5admin", "asset2", "B01", ""})
+	require.NoError(t, err)
+	contribution = chaincode.Contribution{}
+	err = json.Unmarshal(state[key], &contribution)
+	require.NoError(t, err)
+	require.Equal(t, float32(100), contribution.Penalty)
+	require.Nil(t, contribution.Escalation)
+}
@@ -0,0 +1,489 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	governanceObjectType = "governance"
+	proposalObjectType   = "proposal"
+)
+
+// Governance lists the organizations that vote on changes to the scoring policy and the registry.
+// Quorum is the fraction of members that must vote and Approval the fraction of votes that must approve.
+type Governance struct {
+	Members  []string `json:"Members"`
+	Quorum   float32  `json:"Quorum"`
+	Approval float32  `json:"Approval"`
+}
+
+// Change is one change of a proposal. Kind names the policy function it applies and determines
+// which of the other fields are used.
+type Change struct {
+	Kind        string      `json:"Kind"`
+	Checkpoint  string      `json:"Checkpoint,omitempty" metadata:",optional"`
+	Penalty     float32     `json:"Penalty,omitempty" metadata:",optional"`
+	Failures    int         `json:"Failures,omitempty" metadata:",optional"`
+	WindowDays  int         `json:"WindowDays,omitempty" metadata:",optional"`
+	Multiplier  float32     `json:"Multiplier,omitempty" metadata:",optional"`
+	Days        int         `json:"Days,omitempty" metadata:",optional"`
+	Hours       int         `json:"Hours,omitempty" metadata:",optional"`
+	Mandatory   bool        `json:"Mandatory,omitempty" metadata:",optional"`
+	Arbiter     string      `json:"Arbiter,omitempty" metadata:",optional"`
+	Participant string      `json:"Participant,omitempty" metadata:",optional"`
+	Device      string      `json:"Device,omitempty" metadata:",optional"`
+	PublicKey   string      `json:"PublicKey,omitempty" metadata:",optional"`
+	Category    string      `json:"Category,omitempty" metadata:",optional"`
+	Metric      string      `json:"Metric,omitempty" metadata:",optional"`
+	Min         float64     `json:"Min,omitempty" metadata:",optional"`
+	Max         float64     `json:"Max,omitempty" metadata:",optional"`
+	Channel     string      `json:"Channel,omitempty" metadata:",optional"`
+	Chaincode   string      `json:"Chaincode,omitempty" metadata:",optional"`
+	Allowed     bool        `json:"Allowed,omitempty" metadata:",optional"`
+	Grade       string      `json:"Grade,omitempty" metadata:",optional"`
+	Orgs        []string    `json:"Orgs,omitempty" metadata:",optional"`
+	Governance  *Governance `json:"Governance,omitempty" metadata:",optional"`
+}
+
+// Proposal is a set of changes the members vote on. Votes maps the MSPID of each member that voted
+// to its approval. Status is one of "open", "executed", "rejected" or "failed"; Reason explains why an
+// approved proposal failed.
+type Proposal struct {
+	ID       string          `json:"ID"`
+	Proposer string          `json:"Proposer"`
+	Changes  []Change        `json:"Changes"`
+	Deadline time.Time       `json:"Deadline"`
+	Votes    map[string]bool `json:"Votes"`
+	Status   string          `json:"Status"`
+	Reason   string          `json:"Reason,omitempty" metadata:",optional"`
+}
+
+// SetGovernance hands control of the scoring policy and the registry over to the given member
+// organizations. It can only be used once, by the admin of org1; later changes require a proposal.
+func (s *SmartContract) SetGovernance(ctx contractapi.TransactionContextInterface, members []string, quorum float32, approval float32) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putGovernance(ctx, &Governance{Members: members, Quorum: quorum, Approval: approval})
+}
+
+// ReadGovernance returns the governance of the consortium.
+func (s *SmartContract) ReadGovernance(ctx contractapi.TransactionContextInterface) (*Governance, error) {
+	governance, err := s.readGovernance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if governance == nil {
+		return nil, fmt.Errorf("the governance has not been set up")
+	}
+
+	return governance, nil
+}
+
+// ProposeChange opens a proposal to apply the given JSON encoded list of changes, open for
+// votes for the given number of hours from the transaction timestamp. Each change is validated
+// against the current state, so that only changes that can be applied are voted on.
+func (s *SmartContract) ProposeChange(ctx contractapi.TransactionContextInterface, id string, changesJSON string, votingHours int) error {
+	_, mspID, err := s.checkMember(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(proposalObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	proposalJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if proposalJSON != nil {
+		return fmt.Errorf("the proposal %s already exists", id)
+	}
+
+	var changes []Change
+	decoder := json.NewDecoder(bytes.NewReader([]byte(changesJSON)))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&changes)
+	if err != nil {
+		return fmt.Errorf("failed to parse changes: %v", err)
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("a proposal must contain at least one change")
+	}
+	for _, change := range changes {
+		err = s.validateChange(ctx, change)
+		if err != nil {
+			return err
+		}
+	}
+
+	if votingHours <= 0 {
+		return fmt.Errorf("the voting period must be at least one hour")
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	proposal := Proposal{
+		ID:       id,
+		Proposer: mspID,
+		Changes:  changes,
+		Deadline: timestamp.Add(time.Duration(votingHours) * time.Hour),
+		Votes:    map[string]bool{},
+		Status:   "open",
+	}
+
+	return s.putProposal(ctx, &proposal)
+}
+
+// Vote records the caller's organization's vote on an open proposal.
+func (s *SmartContract) Vote(ctx contractapi.TransactionContextInterface, id string, approve bool) error {
+	_, mspID, err := s.checkMember(ctx)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := s.ReadProposal(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if proposal.Status != "open" {
+		return fmt.Errorf("the proposal %s is already %s", id, proposal.Status)
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	if timestamp.After(proposal.Deadline) {
+		return fmt.Errorf("the voting period of proposal %s has ended", id)
+	}
+	if _, ok := proposal.Votes[mspID]; ok {
+		return fmt.Errorf("%s has already voted on proposal %s", mspID, id)
+	}
+
+	proposal.Votes[mspID] = approve
+
+	return s.putProposal(ctx, proposal)
+}
+
+// ExecuteProposal applies all changes of an approved proposal in this transaction. A proposal is
+// approved once the quorum voted and enough members approve that the remaining votes cannot change
+// the outcome, or at its deadline if the quorum voted and enough of the votes approve. A proposal that is not approved
+// at its deadline is rejected. The state may have changed since the proposal was made, so all changes
+// are validated again first: if one of them can no longer be applied, none is and the proposal fails.
+func (s *SmartContract) ExecuteProposal(ctx contractapi.TransactionContextInterface, id string) error {
+	governance, _, err := s.checkMember(ctx)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := s.ReadProposal(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if proposal.Status != "open" {
+		return fmt.Errorf("the proposal %s is already %s", id, proposal.Status)
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	var votes, approvals float32
+	for _, member := range governance.Members {
+		approve, ok := proposal.Votes[member]
+		if !ok {
+			continue
+		}
+		votes++
+		if approve {
+			approvals++
+		}
+	}
+	members := float32(len(governance.Members))
+	closed := timestamp.After(proposal.Deadline)
+	quorum := votes/members >= governance.Quorum
+
+	approved := quorum && approvals/members >= governance.Approval
+	if closed && quorum && approvals/votes >= governance.Approval {
+		approved = true
+	}
+
+	switch {
+	case approved:
+		for _, change := range proposal.Changes {
+			err = s.validateChange(ctx, change)
+			if err != nil {
+				proposal.Status = "failed"
+				proposal.Reason = err.Error()
+				return s.putProposal(ctx, proposal)
+			}
+		}
+		for _, change := range proposal.Changes {
+			err = s.applyChange(ctx, change)
+			if err != nil {
+				return fmt.Errorf("failed to apply %s: %v", change.Kind, err)
+			}
+		}
+		proposal.Status = "executed"
+	case closed:
+		proposal.Status = "rejected"
+	default:
+		return fmt.Errorf("the proposal %s has not been approved yet", id)
+	}
+
+	return s.putProposal(ctx, proposal)
+}
+
+// ReadProposal returns the proposal with the given id.
+func (s *SmartContract) ReadProposal(ctx contractapi.TransactionContextInterface, id string) (*Proposal, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(proposalObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	proposalJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if proposalJSON == nil {
+		return nil, fmt.Errorf("the proposal %s does not exist", id)
+	}
+
+	var proposal Proposal
+	err = json.Unmarshal(proposalJSON, &proposal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proposal, nil
+}
+
+// checkPolicyAdmin allows the admin of org1 to change the scoring policy and the registry directly
+// until a governance has been set up.
+func (s *SmartContract) checkPolicyAdmin(ctx contractapi.TransactionContextInterface) error {
+	governance, err := s.readGovernance(ctx)
+	if err != nil {
+		return err
+	}
+	if governance != nil {
+		return fmt.Errorf("the scoring policy is governed by the consortium, use ProposeChange")
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	if caller != "org1admin" {
+		return fmt.Errorf("only admin from org1 can use this function")
+	}
+
+	return nil
+}
+
+// checkMember returns the governance and the caller's MSPID if the caller is an admin of a member
+// organization.
+func (s *SmartContract) checkMember(ctx contractapi.TransactionContextInterface) (*Governance, string, error) {
+	governance, err := s.ReadGovernance(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get the caller's MSPID: %v", err)
+	}
+
+	if !isMember(governance, mspID) {
+		return nil, "", fmt.Errorf("%s is not a member of the governance", mspID)
+	}
+
+	admin, err := hasAdminRole(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !admin {
+		return nil, "", fmt.Errorf("only admins of %s can act for it in the governance", mspID)
+	}
+
+	return governance, mspID, nil
+}
+
+// isMember reports whether an organization is a member of the governance.
+func isMember(governance *Governance, mspID string) bool {
+	for _, member := range governance.Members {
+		if member == mspID {
+			return true
+		}
+	}
+	return false
+}
+
+// validateChange checks that a change of a proposal can be applied to the current state.
+func (s *SmartContract) validateChange(ctx contractapi.TransactionContextInterface, change Change) error {
+	var err error
+	switch change.Kind {
+	case "SetCheckpointPenalty":
+		err = validateCheckpointPenalty(change.Checkpoint, change.Penalty)
+	case "SetRecallPenalty":
+		err = validateRecallPenalty(change.Penalty)
+	case "SetEscalationRule":
+		err = validateEscalationRule(change.Checkpoint, change.Failures, change.WindowDays, change.Multiplier)
+	case "SetCheckpointValidity":
+		err = validateCheckpointValidity(change.Checkpoint, change.Days)
+	case "SetStageSLA":
+		err = validateStageSLA(change.Checkpoint, change.Hours, change.Penalty)
+	case "SetDualInspection":
+		_, err = findCheckpoint(change.Checkpoint)
+	case "RegisterParticipant":
+		err = s.validateParticipant(ctx, change.Participant)
+	case "RegisterDevice":
+		err = s.validateDevice(ctx, change.Device, change.PublicKey)
+	case "SetTelemetryThreshold":
+		err = validateTelemetryThreshold(change.Metric, change.Min, change.Max, change.Checkpoint)
+	case "SetCreditRegistry":
+		err = s.validateCreditRegistry(ctx, change.Channel, change.Chaincode)
+	case "SetCreditSource":
+		err = validateCreditSource(change.Chaincode, change.Allowed)
+	case "SetMinimumGrade":
+		err = validateMinimumGrade(change.Grade)
+	case "SetScoringOrgs":
+	case "SetGovernance":
+		err = validateGovernance(change.Governance)
+	default:
+		return fmt.Errorf("unsupported change %q", change.Kind)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s change: %v", change.Kind, err)
+	}
+
+	return nil
+}
+
+// applyChange applies one change of an executed proposal.
+func (s *SmartContract) applyChange(ctx contractapi.TransactionContextInterface, change Change) error {
+	switch change.Kind {
+	case "SetCheckpointPenalty":
+		return s.putCheckpointPenalty(ctx, change.Checkpoint, change.Penalty)
+	case "SetRecallPenalty":
+		return s.putRecallPenalty(ctx, change.Penalty)
+	case "SetEscalationRule":
+		return s.putEscalationRule(ctx, change.Checkpoint, change.Failures, change.WindowDays, change.Multiplier)
+	case "SetCheckpointValidity":
+		return s.putCheckpointValidity(ctx, change.Checkpoint, change.Days, change.Mandatory)
+	case "SetStageSLA":
+		return s.putStageSLA(ctx, change.Checkpoint, change.Hours, change.Penalty)
+	case "SetDualInspection":
+		return s.putDualInspection(ctx, change.Checkpoint, change.Arbiter)
+	case "RegisterParticipant":
+		return s.putParticipant(ctx, change.Participant)
+	case "RegisterDevice":
+		return s.putDevice(ctx, change.Device, change.PublicKey)
+	case "SetTelemetryThreshold":
+		return s.putTelemetryThreshold(ctx, change.Category, change.Metric, change.Min, change.Max, change.Checkpoint)
+	case "SetCreditRegistry":
+		return s.putCreditRegistry(ctx, change.Channel, change.Chaincode)
+	case "SetCreditSource":
+		return s.putCreditSource(ctx, change.Channel, change.Chaincode, change.Allowed)
+	case "SetMinimumGrade":
+		return s.putMinimumGrade(ctx, change.Grade)
+	case "SetScoringOrgs":
+		return s.putScoringOrgs(ctx, change.Orgs)
+	case "SetGovernance":
+		return s.putGovernance(ctx, change.Governance)
+	}
+	return fmt.Errorf("unsupported change %q", change.Kind)
+}
+
+// readGovernance returns the governance of the consortium, or nil if none has been set up.
+func (s *SmartContract) readGovernance(ctx contractapi.TransactionContextInterface) (*Governance, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(governanceObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	governanceJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if governanceJSON == nil {
+		return nil, nil
+	}
+
+	var governance Governance
+	err = json.Unmarshal(governanceJSON, &governance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &governance, nil
+}
+
+// validateGovernance checks the governance of the consortium before it is stored.
+func validateGovernance(governance *Governance) error {
+	if governance == nil {
+		return fmt.Errorf("the change has no governance")
+	}
+	if len(governance.Members) == 0 {
+		return fmt.Errorf("the governance must have at least one member")
+	}
+	if governance.Quorum <= 0 || governance.Quorum > 1 {
+		return fmt.Errorf("the quorum must be greater than 0 and at most 1")
+	}
+	if governance.Approval <= 0 || governance.Approval > 1 {
+		return fmt.Errorf("the approval threshold must be greater than 0 and at most 1")
+	}
+
+	return nil
+}
+
+// putGovernance stores the governance of the consortium.
+func (s *SmartContract) putGovernance(ctx contractapi.TransactionContextInterface, governance *Governance) error {
+	err := validateGovernance(governance)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(governanceObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	governanceJSON, err := json.Marshal(governance)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, governanceJSON)
+}
+
+func (s *SmartContract) putProposal(ctx contractapi.TransactionContextInterface, proposal *Proposal) error {
+	key, err := ctx.GetStub().CreateCompositeKey(proposalObjectType, []string{proposal.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	proposalJSON, err := json.Marshal(proposal)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, proposalJSON)
+}
@@ -0,0 +1,188 @@
+package chaincode_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGovernance(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCallerWithMSP(t, chaincodeStub, "org1admin", "Org1MSP", "admin")
+	commit := setState(chaincodeStub, map[string][]byte{})
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.SetGovernance(transactionContext, []string{"Org1MSP", "Org2MSP", "Org5MSP"}, 0.5, 0.6)
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.SetCheckpointPenalty(transactionContext, "B01", 50)
+	commit()
+	require.EqualError(t, err, "the scoring policy is governed by the consortium, use ProposeChange")
+
+	err = assetTransfer.ProposeChange(transactionContext, "p1", `[{"Kind":"SetCheckpointPenalty","Checkpoint":"B01","Penalty":50},{"Kind":"RegisterParticipant","Participant":"org3admin"}]`, 24)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.ProposeChange(transactionContext, "p2", `[{"Kind":"DeleteEverything"}]`, 24)
+	commit()
+	require.EqualError(t, err, `unsupported change "DeleteEverything"`)
+	err = assetTransfer.ProposeChange(transactionContext, "p2", `[{"Kind":"SetCheckpointPenalty","Checkpoint":"B01","Penalty":-5}]`, 24)
+	commit()
+	require.EqualError(t, err, "invalid SetCheckpointPenalty change: the penalty must not be negative")
+	err = assetTransfer.ProposeChange(transactionContext, "p2", `[{"Kind":"SetGovernance"}]`, 24)
+	commit()
+	require.EqualError(t, err, "invalid SetGovernance change: the change has no governance")
+
+	err = assetTransfer.Vote(transactionContext, "p1", true)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.Vote(transactionContext, "p1", true)
+	commit()
+	require.EqualError(t, err, "Org1MSP has already voted on proposal p1")
+
+	err = assetTransfer.ExecuteProposal(transactionContext, "p1")
+	commit()
+	require.EqualError(t, err, "the proposal p1 has not been approved yet")
+
+	setCallerWithMSP(t, chaincodeStub, "org2admin", "Org2MSP", "admin")
+	err = assetTransfer.Vote(transactionContext, "p1", true)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.ExecuteProposal(transactionContext, "p1")
+	commit()
+	require.NoError(t, err)
+
+	proposal, err := assetTransfer.ReadProposal(transactionContext, "p1")
+	require.NoError(t, err)
+	require.Equal(t, "executed", proposal.Status)
+	credit, err := assetTransfer.ReadCredit(transactionContext, "org3admin")
+	require.NoError(t, err)
+	require.Equal(t, "org3admin", credit.ID)
+
+	setCallerWithMSP(t, chaincodeStub, "org4admin", "Org4MSP", "admin")
+	err = assetTransfer.Vote(transactionContext, "p1", false)
+	commit()
+	require.EqualError(t, err, "Org4MSP is not a member of the governance")
+
+	setCallerWithMSP(t, chaincodeStub, "user1", "Org2MSP", "client")
+	err = assetTransfer.ProposeChange(transactionContext, "p4", `[{"Kind":"SetRecallPenalty","Penalty":10}]`, 24)
+	commit()
+	require.EqualError(t, err, "only admins of Org2MSP can act for it in the governance")
+	err = assetTransfer.Vote(transactionContext, "p1", false)
+	commit()
+	require.EqualError(t, err, "only admins of Org2MSP can act for it in the governance")
+
+	setCallerWithMSP(t, chaincodeStub, "org5admin", "Org5MSP", "admin")
+	err = assetTransfer.ProposeChange(transactionContext, "p3", `[{"Kind":"SetCheckpointPenalty","Checkpoint":"B01","Penalty":10}]`, 1)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.Vote(transactionContext, "p3", false)
+	commit()
+	require.NoError(t, err)
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 16, 0, 0, 0, 0, time.UTC)), nil)
+	err = assetTransfer.Vote(transactionContext, "p3", true)
+	commit()
+	require.EqualError(t, err, "the voting period of proposal p3 has ended")
+	err = assetTransfer.ExecuteProposal(transactionContext, "p3")
+	commit()
+	require.NoError(t, err)
+	proposal, err = assetTransfer.ReadProposal(transactionContext, "p3")
+	require.NoError(t, err)
+	require.Equal(t, "rejected", proposal.Status)
+}
+
+func TestGovernanceFailedProposal(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCallerWithMSP(t, chaincodeStub, "org1admin", "Org1MSP", "admin")
+	state := map[string][]byte{}
+	commit := setState(chaincodeStub, state)
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.SetGovernance(transactionContext, []string{"Org1MSP"}, 1, 1)
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.ProposeChange(transactionContext, "p1", `[{"Kind":"RegisterParticipant","Participant":"org3admin"}]`, 24)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.ProposeChange(transactionContext, "p2", `[{"Kind":"SetCheckpointPenalty","Checkpoint":"B01","Penalty":50},{"Kind":"RegisterParticipant","Participant":"org3admin"}]`, 24)
+	commit()
+	require.NoError(t, err)
+
+	for _, id := range []string{"p1", "p2"} {
+		err = assetTransfer.Vote(transactionContext, id, true)
+		commit()
+		require.NoError(t, err)
+		err = assetTransfer.ExecuteProposal(transactionContext, id)
+		commit()
+		require.NoError(t, err)
+	}
+
+	proposal, err := assetTransfer.ReadProposal(transactionContext, "p2")
+	require.NoError(t, err)
+	require.Equal(t, "failed", proposal.Status, "a change that can no longer be applied fails the proposal")
+	require.Equal(t, "invalid RegisterParticipant change: the credit org3admin already exists", proposal.Reason)
+	key, err := shim.CreateCompositeKey("penalty", []string{"B01"})
+	require.NoError(t, err)
+	require.NotContains(t, state, key, "no change of a failed proposal is applied")
+
+	err = assetTransfer.ExecuteProposal(transactionContext, "p2")
+	require.EqualError(t, err, "the proposal p2 is already failed")
+}
+
+func TestGovernanceQuorum(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCallerWithMSP(t, chaincodeStub, "org1admin", "Org1MSP", "admin")
+	commit := setState(chaincodeStub, map[string][]byte{})
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.SetGovernance(transactionContext, []string{"Org1MSP", "Org2MSP", "Org3MSP", "Org5MSP"}, 0.8, 0.5)
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.ProposeChange(transactionContext, "p1", `[{"Kind":"SetCheckpointPenalty","Checkpoint":"B01","Penalty":50}]`, 24)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.Vote(transactionContext, "p1", true)
+	commit()
+	require.NoError(t, err)
+	setCallerWithMSP(t, chaincodeStub, "org2admin", "Org2MSP", "admin")
+	err = assetTransfer.Vote(transactionContext, "p1", true)
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.ExecuteProposal(transactionContext, "p1")
+	commit()
+	require.EqualError(t, err, "the proposal p1 has not been approved yet", "2 of 4 members are below the quorum")
+
+	setCallerWithMSP(t, chaincodeStub, "org3admin", "Org3MSP", "admin")
+	err = assetTransfer.Vote(transactionContext, "p1", false)
+	commit()
+	require.NoError(t, err)
+	setCallerWithMSP(t, chaincodeStub, "org5admin", "Org5MSP", "admin")
+	err = assetTransfer.Vote(transactionContext, "p1", false)
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.ExecuteProposal(transactionContext, "p1")
+	commit()
+	require.NoError(t, err)
+	proposal, err := assetTransfer.ReadProposal(transactionContext, "p1")
+	require.NoError(t, err)
+	require.Equal(t, "executed", proposal.Status)
+}
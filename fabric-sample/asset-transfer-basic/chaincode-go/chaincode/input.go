@@ -0,0 +1,191 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// InspectionResult is the outcome of a checkpoint: "pass", "fail" or "not_applicable". When an asset is
+// created a stage may also be "pending", to be completed later with CompleteStage.
+type InspectionResult string
+
+const (
+	ResultPass          InspectionResult = "pass"
+	ResultFail          InspectionResult = "fail"
+	ResultNotApplicable InspectionResult = "not_applicable"
+	ResultPending       InspectionResult = "pending"
+)
+
+// InspectionInput is the structured input of CreateAssetFromInspection, one result per checkpoint.
+// As a typed parameter it is published in the contract metadata (org.hyperledger.fabric:GetMetadata),
+// and GetInspectionSchema adds the allowed result values.
+type InspectionInput struct {
+	ID                      string           `json:"ID"`
+	Category                string           `json:"Category,omitempty" metadata:",optional"`
+	AcceptanceSampling      InspectionResult `json:"AcceptanceSampling"`
+	ManufacturingEquipment  InspectionResult `json:"ManufacturingEquipment"`
+	TransportationEquipment InspectionResult `json:"TransportationEquipment"`
+	InventoryManagement     InspectionResult `json:"InventoryManagement"`
+	SaveEquipment           InspectionResult `json:"SaveEquipment"`
+	A01                     InspectionResult `json:"A01"`
+	A02                     InspectionResult `json:"A02"`
+	A03                     InspectionResult `json:"A03"`
+	A04                     InspectionResult `json:"A04"`
+	B01                     InspectionResult `json:"B01"`
+	C01                     InspectionResult `json:"C01"`
+}
+
+// SourceInspectionInput is the structured input of UpdateAssetFromInspection, the results of the
+// checkpoints the source reports about itself
+type SourceInspectionInput struct {
+	ID                      string           `json:"ID"`
+	AcceptanceSampling      InspectionResult `json:"AcceptanceSampling"`
+	ManufacturingEquipment  InspectionResult `json:"ManufacturingEquipment"`
+	TransportationEquipment InspectionResult `json:"TransportationEquipment"`
+	InventoryManagement     InspectionResult `json:"InventoryManagement"`
+	SaveEquipment           InspectionResult `json:"SaveEquipment"`
+}
+
+// CreateAssetFromInspection creates an asset from structured inspection results. Checkpoints that are
+// not applicable are recorded as such and neither scored nor penalized. Pending stages are scored when
+// they are completed, and their SLAs run from the last completed stage before them. It returns the
+// created asset.
+func (s *SmartContract) CreateAssetFromInspection(ctx contractapi.TransactionContextInterface, inspection InspectionInput) (*Asset, error) {
+	results := map[string]InspectionResult{
+		"AcceptanceSampling":      inspection.AcceptanceSampling,
+		"ManufacturingEquipment":  inspection.ManufacturingEquipment,
+		"TransportationEquipment": inspection.TransportationEquipment,
+		"InventoryManagement":     inspection.InventoryManagement,
+		"SaveEquipment":           inspection.SaveEquipment,
+		"A01":                     inspection.A01,
+		"A02":                     inspection.A02,
+		"A03":                     inspection.A03,
+		"A04":                     inspection.A04,
+		"B01":                     inspection.B01,
+		"C01":                     inspection.C01,
+	}
+
+	asset := Asset{ID: inspection.ID, Category: inspection.Category}
+	err := applyInspectionResults(&asset, checkpointNames(), results, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.createAsset(ctx, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// UpdateAssetFromInspection updates the source checkpoints of an asset from structured inspection results.
+func (s *SmartContract) UpdateAssetFromInspection(ctx contractapi.TransactionContextInterface, inspection SourceInspectionInput) error {
+	results := map[string]InspectionResult{
+		"AcceptanceSampling":      inspection.AcceptanceSampling,
+		"ManufacturingEquipment":  inspection.ManufacturingEquipment,
+		"TransportationEquipment": inspection.TransportationEquipment,
+		"InventoryManagement":     inspection.InventoryManagement,
+		"SaveEquipment":           inspection.SaveEquipment,
+	}
+
+	asset := Asset{ID: inspection.ID}
+	err := applyInspectionResults(&asset, sourceCheckpoints, results, false)
+	if err != nil {
+		return err
+	}
+
+	return s.updateAsset(ctx, &asset)
+}
+
+// GetInspectionSchema returns the JSON schema of InspectionInput including the allowed result values,
+// which the generated contract metadata cannot express, so that clients can validate before endorsing.
+func (s *SmartContract) GetInspectionSchema(ctx contractapi.TransactionContextInterface) (string, error) {
+	properties := map[string]interface{}{
+		"ID":       map[string]interface{}{"type": "string", "minLength": 1},
+		"Category": map[string]interface{}{"type": "string"},
+	}
+	required := []string{"ID"}
+	for _, name := range checkpointNames() {
+		properties[name] = map[string]interface{}{
+			"type": "string",
+			"enum": []InspectionResult{ResultPass, ResultFail, ResultNotApplicable, ResultPending},
+		}
+		required = append(required, name)
+	}
+
+	schema := map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-04/schema#",
+		"title":                "InspectionInput",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+
+	return string(schemaJSON), nil
+}
+
+// applyInspectionResults validates the results of the named checkpoints and records them on the asset.
+// Pending results are only accepted when allowPending is set. All invalid fields are reported in a single error.
+func applyInspectionResults(asset *Asset, names []string, results map[string]InspectionResult, allowPending bool) error {
+	var invalid []string
+	if asset.ID == "" {
+		invalid = append(invalid, "ID: required")
+	}
+
+	for _, name := range names {
+		switch results[name] {
+		case ResultPass:
+			setCheckpointResult(asset, name, 1)
+		case ResultFail:
+			setCheckpointResult(asset, name, 0)
+		case ResultNotApplicable:
+			setCheckpointResult(asset, name, resultNotApplicable)
+		case ResultPending:
+			if !allowPending {
+				invalid = append(invalid, fmt.Sprintf("%s: expected pass, fail or not_applicable, got %q", name, results[name]))
+				continue
+			}
+			setCheckpointResult(asset, name, resultPending)
+		case "":
+			invalid = append(invalid, fmt.Sprintf("%s: required", name))
+		default:
+			expected := "pass, fail or not_applicable"
+			if allowPending {
+				expected = "pass, fail, not_applicable or pending"
+			}
+			invalid = append(invalid, fmt.Sprintf("%s: expected %s, got %q", name, expected, results[name]))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid inspection: %s", strings.Join(invalid, "; "))
+	}
+
+	return nil
+}
+
+// validateResults checks the positional results of the named checkpoints, which must be 1 for a pass or 0 for a failure.
+func validateResults(names []string, results []int) error {
+	var invalid []string
+	for i, name := range names {
+		if results[i] != 0 && results[i] != 1 {
+			invalid = append(invalid, fmt.Sprintf("%s: expected 0 or 1, got %d", name, results[i]))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid inspection: %s", strings.Join(invalid, "; "))
+	}
+
+	return nil
+}
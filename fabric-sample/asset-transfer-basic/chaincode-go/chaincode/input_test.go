@@ -0,0 +1,150 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAssetFromInspection(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit})
+
+	inspection := chaincode.InspectionInput{
+		ID:                      "asset1",
+		Category:                "dry-goods",
+		AcceptanceSampling:      chaincode.ResultPass,
+		ManufacturingEquipment:  chaincode.ResultPass,
+		TransportationEquipment: chaincode.ResultFail,
+		InventoryManagement:     chaincode.ResultPass,
+		SaveEquipment:           chaincode.ResultPass,
+		A01:                     chaincode.ResultPass,
+		A02:                     chaincode.ResultPass,
+		A03:                     chaincode.ResultPass,
+		A04:                     chaincode.ResultPass,
+		B01:                     chaincode.ResultNotApplicable,
+		C01:                     chaincode.ResultPass,
+	}
+
+	assetTransfer := chaincode.SmartContract{}
+	invalid := inspection
+	invalid.A02 = "passed"
+	invalid.C01 = ""
+	_, err = assetTransfer.CreateAssetFromInspection(transactionContext, invalid)
+	commit()
+	require.EqualError(t, err, `invalid inspection: A02: expected pass, fail, not_applicable or pending, got "passed"; C01: required`)
+
+	created, err := assetTransfer.CreateAssetFromInspection(transactionContext, inspection)
+	commit()
+	require.NoError(t, err)
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	stored, err := json.Marshal(asset)
+	require.NoError(t, err)
+	returned, err := json.Marshal(created)
+	require.NoError(t, err)
+	require.JSONEq(t, string(stored), string(returned), "the created asset is returned")
+	require.Equal(t, "dry-goods", asset.Category)
+	require.Equal(t, 0, asset.TransportationEquipment)
+	require.Equal(t, -1, asset.B01)
+	require.NotContains(t, asset.CheckedAt, "B01")
+
+	org5, err := assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(0), org5.Transaction, "checkpoints that do not apply are not scored")
+	org1, err := assetTransfer.ReadCredit(transactionContext, "org1admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(0.8), org1.Score)
+
+	err = assetTransfer.UpdateAssetFromInspection(transactionContext, chaincode.SourceInspectionInput{
+		ID:                      "asset1",
+		AcceptanceSampling:      chaincode.ResultPass,
+		ManufacturingEquipment:  chaincode.ResultPass,
+		TransportationEquipment: chaincode.ResultPass,
+		InventoryManagement:     chaincode.ResultNotApplicable,
+		SaveEquipment:           chaincode.ResultPass,
+	})
+	commit()
+	require.NoError(t, err)
+	asset, err = assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, 1, asset.TransportationEquipment)
+	require.Equal(t, -1, asset.InventoryManagement)
+
+	err = assetTransfer.UpdateAssetFromInspection(transactionContext, chaincode.SourceInspectionInput{
+		ID:                      "asset1",
+		AcceptanceSampling:      chaincode.ResultPending,
+		ManufacturingEquipment:  chaincode.ResultPass,
+		TransportationEquipment: chaincode.ResultPass,
+		InventoryManagement:     chaincode.ResultPass,
+		SaveEquipment:           chaincode.ResultPass,
+	})
+	commit()
+	require.EqualError(t, err, `invalid inspection: AcceptanceSampling: expected pass, fail or not_applicable, got "pending"`, "a completed stage cannot become pending again")
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset2", 1, 1, 1, 1, 1, 1, 2, 1, 1, -5, 1)
+	commit()
+	require.EqualError(t, err, "invalid inspection: A02: expected 0 or 1, got 2; B01: expected 0 or 1, got -5")
+}
+
+func TestInspectionInputMetadata(t *testing.T) {
+	assetChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+
+	stub := shimtest.NewMockStub("basic", assetChaincode)
+	response := stub.MockInvoke("tx1", [][]byte{[]byte("org.hyperledger.fabric:GetMetadata")})
+	require.Equal(t, int32(shim.OK), response.Status, response.Message)
+
+	var metadata struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Type string `json:"type"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	err = json.Unmarshal(response.Payload, &metadata)
+	require.NoError(t, err)
+
+	schema, ok := metadata.Components.Schemas["InspectionInput"]
+	require.True(t, ok, "InspectionInput is published in the contract metadata")
+	require.Equal(t, "string", schema.Properties["B01"].Type)
+	require.Len(t, schema.Required, 12, "every checkpoint and the ID are required")
+
+	response = stub.MockInvoke("tx2", [][]byte{[]byte("GetInspectionSchema")})
+	require.Equal(t, int32(shim.OK), response.Status, response.Message)
+
+	var inspectionSchema struct {
+		Properties map[string]struct {
+			Type string   `json:"type"`
+			Enum []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	err = json.Unmarshal(response.Payload, &inspectionSchema)
+	require.NoError(t, err)
+	require.Len(t, inspectionSchema.Required, 12, "every checkpoint and the ID are required")
+	for name := range schema.Properties {
+		if name == "ID" || name == "Category" {
+			require.Empty(t, inspectionSchema.Properties[name].Enum)
+			continue
+		}
+		require.Equal(t, []string{"pass", "fail", "not_applicable", "pending"}, inspectionSchema.Properties[name].Enum, name)
+	}
+}
@@ -0,0 +1,503 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	dualInspectionObjectType = "dualInspection"
+	inspectionObjectType     = "inspection"
+	conflictObjectType       = "conflict"
+	reliabilityObjectType    = "reliability"
+)
+
+// DualInspection marks a checkpoint as requiring reports from two independent organizations
+// and names the arbiter who decides when they disagree
+type DualInspection struct {
+	Checkpoint string `json:"Checkpoint"`
+	Arbiter    string `json:"Arbiter"`
+}
+
+// InspectionReport is the result one inspector reported for a checkpoint
+type InspectionReport struct {
+	Inspector string    `json:"Inspector"`
+	MSPID     string    `json:"MSPID"`
+	Passed    bool      `json:"Passed"`
+	TimeStamp time.Time `json:"TimeStamp"`
+}
+
+// Inspection collects the reports for a dual inspected checkpoint of an asset.
+// Status is one of "pending", "agreed", "conflict" or "resolved".
+type Inspection struct {
+	AssetID    string             `json:"AssetID"`
+	Checkpoint string             `json:"Checkpoint"`
+	Reports    []InspectionReport `json:"Reports"`
+	Status     string             `json:"Status"`
+}
+
+// Conflict is raised when the two inspectors of a checkpoint disagree. Status is "open" until the
+// arbiter decides the result, then "resolved".
+type Conflict struct {
+	AssetID    string             `json:"AssetID"`
+	Checkpoint string             `json:"Checkpoint"`
+	Arbiter    string             `json:"Arbiter"`
+	Reports    []InspectionReport `json:"Reports"`
+	Status     string             `json:"Status"`
+	Passed     bool               `json:"Passed"`
+	ResolvedAt time.Time          `json:"ResolvedAt"`
+}
+
+// Reliability tracks how often an inspector's reports matched the decided result
+type Reliability struct {
+	Inspector  string  `json:"Inspector"`
+	Reports    float32 `json:"Reports"`
+	Correct    float32 `json:"Correct"`
+	FinalScore float32 `json:"FinalScore"`
+}
+
+// SetDualInspection requires the checkpoint to be reported by two independent organizations, with
+// disagreements decided by the arbiter. An empty arbiter removes the requirement.
+func (s *SmartContract) SetDualInspection(ctx contractapi.TransactionContextInterface, checkpointName string, arbiter string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putDualInspection(ctx, checkpointName, arbiter)
+}
+
+// ReportInspection records the caller's result for a dual inspected checkpoint of an asset. The
+// checkpoint is scored once a second organization reports the same result; if it reports a
+// different result a Conflict is raised for the arbiter to resolve. Once the decided result has
+// expired, a report starts a new round, which is how dual inspected checkpoints are re-certified.
+func (s *SmartContract) ReportInspection(ctx contractapi.TransactionContextInterface, assetID string, checkpointName string, result int) error {
+	err := validateResults([]string{checkpointName}, []int{result})
+	if err != nil {
+		return err
+	}
+
+	dual, err := s.readDualInspection(ctx, checkpointName)
+	if err != nil {
+		return err
+	}
+	if dual == nil {
+		return fmt.Errorf("the checkpoint %s does not require dual inspection", checkpointName)
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get the caller's MSPID: %v", err)
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	inspection, err := s.ReadInspection(ctx, assetID, checkpointName)
+	if err != nil {
+		return err
+	}
+	if inspection.Status == "agreed" || inspection.Status == "resolved" {
+		expired, err := s.checkpointExpired(ctx, asset, checkpointName, timestamp)
+		if err != nil {
+			return err
+		}
+		if expired {
+			// the decided result has expired, re-certification starts a new round of reports
+			inspection = &Inspection{AssetID: assetID, Checkpoint: checkpointName, Reports: []InspectionReport{}, Status: "pending"}
+		}
+	}
+	if inspection.Status != "pending" {
+		return fmt.Errorf("the inspection of %s for asset %s is already %s", checkpointName, assetID, inspection.Status)
+	}
+	for _, report := range inspection.Reports {
+		if report.MSPID == mspID {
+			return fmt.Errorf("the checkpoint %s of asset %s has already been reported by %s", checkpointName, assetID, mspID)
+		}
+	}
+
+	inspection.Reports = append(inspection.Reports, InspectionReport{
+		Inspector: caller,
+		MSPID:     mspID,
+		Passed:    result != 0,
+		TimeStamp: timestamp,
+	})
+
+	if len(inspection.Reports) == 2 {
+		if inspection.Reports[0].Passed == inspection.Reports[1].Passed {
+			inspection.Status = "agreed"
+			err = s.decideInspection(ctx, caller, inspection, inspection.Reports[0].Passed)
+			if err != nil {
+				return err
+			}
+		} else {
+			inspection.Status = "conflict"
+			conflict := Conflict{
+				AssetID:    assetID,
+				Checkpoint: checkpointName,
+				Arbiter:    dual.Arbiter,
+				Reports:    inspection.Reports,
+				Status:     "open",
+			}
+			err = s.putConflict(ctx, &conflict)
+			if err != nil {
+				return err
+			}
+
+			conflictJSON, err := json.Marshal(conflict)
+			if err != nil {
+				return err
+			}
+			err = ctx.GetStub().SetEvent("Conflict", conflictJSON)
+			if err != nil {
+				return fmt.Errorf("failed to set event: %v", err)
+			}
+		}
+	}
+
+	return s.putInspection(ctx, inspection)
+}
+
+// ResolveConflict decides the result of a checkpoint the inspectors disagreed on. Only the arbiter
+// of the conflict can resolve it.
+func (s *SmartContract) ResolveConflict(ctx contractapi.TransactionContextInterface, assetID string, checkpointName string, result int) error {
+	err := validateResults([]string{checkpointName}, []int{result})
+	if err != nil {
+		return err
+	}
+
+	conflict, err := s.ReadConflict(ctx, assetID, checkpointName)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	if conflict.Arbiter != caller {
+		return fmt.Errorf("only the arbiter %s can resolve this conflict", conflict.Arbiter)
+	}
+	if conflict.Status != "open" {
+		return fmt.Errorf("the conflict of %s for asset %s is already %s", checkpointName, assetID, conflict.Status)
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	conflict.Status = "resolved"
+	conflict.Passed = result != 0
+	conflict.ResolvedAt = timestamp
+	err = s.putConflict(ctx, conflict)
+	if err != nil {
+		return err
+	}
+
+	inspection, err := s.ReadInspection(ctx, assetID, checkpointName)
+	if err != nil {
+		return err
+	}
+	inspection.Status = "resolved"
+
+	err = s.decideInspection(ctx, caller, inspection, conflict.Passed)
+	if err != nil {
+		return err
+	}
+
+	return s.putInspection(ctx, inspection)
+}
+
+// ReadInspection returns the reports for a dual inspected checkpoint of an asset.
+func (s *SmartContract) ReadInspection(ctx contractapi.TransactionContextInterface, assetID string, checkpointName string) (*Inspection, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(inspectionObjectType, []string{assetID, checkpointName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	inspectionJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if inspectionJSON == nil {
+		return &Inspection{AssetID: assetID, Checkpoint: checkpointName, Reports: []InspectionReport{}, Status: "pending"}, nil
+	}
+
+	var inspection Inspection
+	err = json.Unmarshal(inspectionJSON, &inspection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &inspection, nil
+}
+
+// ReadConflict returns the conflict raised for a checkpoint of an asset.
+func (s *SmartContract) ReadConflict(ctx contractapi.TransactionContextInterface, assetID string, checkpointName string) (*Conflict, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(conflictObjectType, []string{assetID, checkpointName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	conflictJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if conflictJSON == nil {
+		return nil, fmt.Errorf("no conflict exists for %s of asset %s", checkpointName, assetID)
+	}
+
+	var conflict Conflict
+	err = json.Unmarshal(conflictJSON, &conflict)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conflict, nil
+}
+
+// GetOpenConflicts returns all conflicts still waiting for their arbiter.
+func (s *SmartContract) GetOpenConflicts(ctx contractapi.TransactionContextInterface) ([]*Conflict, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(conflictObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*Conflict
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var conflict Conflict
+		err = json.Unmarshal(queryResponse.Value, &conflict)
+		if err != nil {
+			return nil, err
+		}
+
+		if conflict.Status == "open" {
+			result = append(result, &conflict)
+		}
+	}
+
+	return result, nil
+}
+
+// ReadReliability returns how reliable an inspector's dual inspection reports have been.
+func (s *SmartContract) ReadReliability(ctx contractapi.TransactionContextInterface, inspector string) (*Reliability, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(reliabilityObjectType, []string{inspector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	reliabilityJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if reliabilityJSON == nil {
+		return nil, fmt.Errorf("the reliability of %s does not exist", inspector)
+	}
+
+	var reliability Reliability
+	err = json.Unmarshal(reliabilityJSON, &reliability)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reliability, nil
+}
+
+// readDualInspection returns the dual inspection requirement of a checkpoint, or nil if it has none.
+func (s *SmartContract) readDualInspection(ctx contractapi.TransactionContextInterface, checkpointName string) (*DualInspection, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(dualInspectionObjectType, []string{checkpointName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	dualJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if dualJSON == nil {
+		return nil, nil
+	}
+
+	var dual DualInspection
+	err = json.Unmarshal(dualJSON, &dual)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dual, nil
+}
+
+// decideInspection records the decided result on the asset, applies it to the credit of the
+// participant the checkpoint is scored against and updates the reliability of the inspectors.
+func (s *SmartContract) decideInspection(ctx contractapi.TransactionContextInterface, rater string, inspection *Inspection, passed bool) error {
+	asset, err := s.ReadAsset(ctx, inspection.AssetID)
+	if err != nil {
+		return err
+	}
+
+	c, err := findCheckpoint(inspection.Checkpoint)
+	if err != nil {
+		return err
+	}
+
+	result := 0
+	if passed {
+		result = 1
+	}
+	setCheckpointResult(asset, c.Name, result)
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	participant := c.participant(asset)
+	contribution := Contribution{Participant: participant, Checkpoint: c.Name, Passed: passed}
+
+	var score float32 = 1
+	if !passed {
+		err = s.applyPenalty(ctx, c, timestamp, &contribution)
+		if err != nil {
+			return err
+		}
+		score = (score*100 - contribution.Penalty) / 100
+	}
+	err = s.updateCredit(ctx, participant, score, asset.ID+"/"+c.Name)
+	if err != nil {
+		return err
+	}
+
+	setCheckedAt(asset, c.Name, timestamp)
+
+	err = s.putContributions(ctx, rater, asset.ID, timestamp, []Contribution{contribution})
+	if err != nil {
+		return err
+	}
+
+	for _, report := range inspection.Reports {
+		err = s.updateReliability(ctx, report.Inspector, report.Passed == passed)
+		if err != nil {
+			return err
+		}
+	}
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(asset.ID, assetJSON)
+}
+
+// updateReliability counts one report of the inspector, correct or not.
+func (s *SmartContract) updateReliability(ctx contractapi.TransactionContextInterface, inspector string, correct bool) error {
+	key, err := ctx.GetStub().CreateCompositeKey(reliabilityObjectType, []string{inspector})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	reliabilityJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	reliability := Reliability{Inspector: inspector}
+	if reliabilityJSON != nil {
+		err = json.Unmarshal(reliabilityJSON, &reliability)
+		if err != nil {
+			return err
+		}
+	}
+
+	if correct {
+		reliability.Correct += 1
+	}
+	reliability.Reports += 1
+	reliability.FinalScore = reliability.Correct / reliability.Reports
+
+	reliabilityJSON, err = json.Marshal(reliability)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, reliabilityJSON)
+}
+
+func (s *SmartContract) putInspection(ctx contractapi.TransactionContextInterface, inspection *Inspection) error {
+	key, err := ctx.GetStub().CreateCompositeKey(inspectionObjectType, []string{inspection.AssetID, inspection.Checkpoint})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	inspectionJSON, err := json.Marshal(inspection)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, inspectionJSON)
+}
+
+func (s *SmartContract) putConflict(ctx contractapi.TransactionContextInterface, conflict *Conflict) error {
+	key, err := ctx.GetStub().CreateCompositeKey(conflictObjectType, []string{conflict.AssetID, conflict.Checkpoint})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	conflictJSON, err := json.Marshal(conflict)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, conflictJSON)
+}
+
+// putDualInspection stores the dual inspection requirement of a checkpoint.
+func (s *SmartContract) putDualInspection(ctx contractapi.TransactionContextInterface, checkpointName string, arbiter string) error {
+	_, err := findCheckpoint(checkpointName)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(dualInspectionObjectType, []string{checkpointName})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if arbiter == "" {
+		return ctx.GetStub().DelState(key)
+	}
+
+	dual := DualInspection{
+		Checkpoint: checkpointName,
+		Arbiter:    arbiter,
+	}
+	dualJSON, err := json.Marshal(dual)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, dualJSON)
+}
@@ -0,0 +1,88 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualInspection(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetDualInspection(transactionContext, "B01", "org3admin")
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	commit()
+	require.NoError(t, err)
+	org5, err := assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(0), org5.Transaction, "B01 is not scored before the inspection is decided")
+
+	err = assetTransfer.ReportInspection(transactionContext, "asset1", "B01", 1)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.ReportInspection(transactionContext, "asset1", "B01", 0)
+	commit()
+	require.EqualError(t, err, "the checkpoint B01 of asset asset1 has already been reported by Org1MSP")
+
+	setCallerWithMSP(t, chaincodeStub, "org2admin", "Org2MSP")
+	err = assetTransfer.ReportInspection(transactionContext, "asset1", "B01", 0)
+	commit()
+	require.NoError(t, err)
+	require.Equal(t, "Conflict", func() string { name, _ := chaincodeStub.SetEventArgsForCall(0); return name }())
+
+	conflicts, err := assetTransfer.GetOpenConflicts(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	require.Equal(t, "org3admin", conflicts[0].Arbiter)
+
+	err = assetTransfer.ResolveConflict(transactionContext, "asset1", "B01", 0)
+	commit()
+	require.EqualError(t, err, "only the arbiter org3admin can resolve this conflict")
+
+	setCallerWithMSP(t, chaincodeStub, "org3admin", "Org3MSP")
+	err = assetTransfer.ResolveConflict(transactionContext, "asset1", "B01", 7)
+	commit()
+	require.EqualError(t, err, "invalid inspection: B01: expected 0 or 1, got 7")
+	err = assetTransfer.ResolveConflict(transactionContext, "asset1", "B01", 0)
+	commit()
+	require.NoError(t, err)
+
+	org5, err = assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{Grade: "D"}, org5, "other members only see the grade")
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, 0, asset.B01)
+
+	wrong, err := assetTransfer.ReadReliability(transactionContext, "org1admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(0), wrong.FinalScore)
+	right, err := assetTransfer.ReadReliability(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(1), right.FinalScore)
+
+	err = assetTransfer.ResolveConflict(transactionContext, "asset1", "B01", 1)
+	commit()
+	require.EqualError(t, err, "the conflict of B01 for asset asset1 is already resolved")
+
+	setCaller(t, chaincodeStub, "org1admin")
+	org5, err = assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Credit{Transaction: 1, Score: 0, FinalScore: 0, Grade: "D"}, org5)
+}
@@ -0,0 +1,395 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	recallObjectType        = "recall"
+	recallPenaltyObjectType = "recallPenalty"
+	lineageObjectType       = "lineage"
+)
+
+// defaultRecallPenalty is the number of percentage points deducted from the responsible party when
+// no recall penalty has been set
+const defaultRecallPenalty float32 = 100
+
+// AssetRecall is the recall status shown on each affected asset
+type AssetRecall struct {
+	ID           string `json:"ID"`
+	Reason       string `json:"Reason"`
+	Acknowledged bool   `json:"Acknowledged"`
+}
+
+// Recall flags an asset and every asset derived from it as defective. Holders maps the owner of each
+// affected asset to whether it has acknowledged the recall. Status is "active" until all holders
+// have acknowledged it, then "acknowledged".
+type Recall struct {
+	ID          string          `json:"ID"`
+	AssetID     string          `json:"AssetID"`
+	Reason      string          `json:"Reason"`
+	Initiator   string          `json:"Initiator"`
+	Responsible string          `json:"Responsible"`
+	Penalty     float32         `json:"Penalty"`
+	Assets      []string        `json:"Assets"`
+	Holders     map[string]bool `json:"Holders"`
+	Status      string          `json:"Status"`
+	TimeStamp   time.Time       `json:"TimeStamp"`
+}
+
+// LinkAsset records that an asset was derived from a parent asset, so that recalls of the parent reach it.
+func (s *SmartContract) LinkAsset(ctx contractapi.TransactionContextInterface, id string, parentID string) error {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	exists, err := s.AssetExists(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("the asset %s does not exist", parentID)
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	if asset.Owner != caller {
+		return fmt.Errorf("only the owner of the asset can link it")
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(lineageObjectType, []string{parentID, id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, []byte{0x00})
+}
+
+// SetRecallPenalty sets the number of percentage points deducted from the party responsible for a recalled asset.
+func (s *SmartContract) SetRecallPenalty(ctx contractapi.TransactionContextInterface, penalty float32) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putRecallPenalty(ctx, penalty)
+}
+
+// InitiateRecall recalls an asset and its lineage, penalizes the source of the asset and emits a
+// Recall event naming the holders, each of which must acknowledge it. Only the source of the asset
+// or the admin of org1 can initiate a recall.
+func (s *SmartContract) InitiateRecall(ctx contractapi.TransactionContextInterface, assetID string, reason string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	responsible := asset.Source
+	if responsible == "" {
+		responsible = asset.Owner
+	}
+	if caller != responsible && caller != "org1admin" {
+		return fmt.Errorf("only the source of the asset or admin from org1 can recall it")
+	}
+
+	if asset.Recall != nil {
+		return fmt.Errorf("the asset %s has already been recalled", assetID)
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	penalty, err := s.recallPenalty(ctx)
+	if err != nil {
+		return err
+	}
+
+	ids, err := s.lineage(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	recall := Recall{
+		ID:          assetID,
+		AssetID:     assetID,
+		Reason:      reason,
+		Initiator:   caller,
+		Responsible: responsible,
+		Penalty:     penalty,
+		Assets:      []string{},
+		Holders:     map[string]bool{},
+		Status:      "active",
+		TimeStamp:   timestamp,
+	}
+
+	for _, id := range ids {
+		affected, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return err
+		}
+		if affected.Recall != nil {
+			continue
+		}
+
+		affected.Recall = &AssetRecall{ID: recall.ID, Reason: reason}
+		assetJSON, err := json.Marshal(affected)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(id, assetJSON)
+		if err != nil {
+			return err
+		}
+
+		recall.Assets = append(recall.Assets, id)
+		recall.Holders[affected.Owner] = false
+	}
+
+	err = s.deductCredit(ctx, responsible, penalty, "recall/"+recall.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.putRecall(ctx, &recall)
+	if err != nil {
+		return err
+	}
+
+	recallJSON, err := json.Marshal(recall)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent("Recall", recallJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return nil
+}
+
+// AcknowledgeRecall records that the caller has acknowledged the recall for every affected asset it holds.
+func (s *SmartContract) AcknowledgeRecall(ctx contractapi.TransactionContextInterface, recallID string) error {
+	recall, err := s.ReadRecall(ctx, recallID)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	acknowledged, ok := recall.Holders[caller]
+	if !ok {
+		return fmt.Errorf("%s holds no asset affected by recall %s", caller, recallID)
+	}
+	if acknowledged {
+		return fmt.Errorf("%s has already acknowledged recall %s", caller, recallID)
+	}
+
+	for _, id := range recall.Assets {
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return err
+		}
+		if asset.Owner != caller || asset.Recall == nil {
+			continue
+		}
+
+		asset.Recall.Acknowledged = true
+		assetJSON, err := json.Marshal(asset)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(id, assetJSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	recall.Holders[caller] = true
+	recall.Status = "acknowledged"
+	for _, acknowledged := range recall.Holders {
+		if !acknowledged {
+			recall.Status = "active"
+		}
+	}
+
+	return s.putRecall(ctx, recall)
+}
+
+// ReadRecall returns the recall with the given id.
+func (s *SmartContract) ReadRecall(ctx contractapi.TransactionContextInterface, id string) (*Recall, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(recallObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	recallJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recallJSON == nil {
+		return nil, fmt.Errorf("the recall %s does not exist", id)
+	}
+
+	var recall Recall
+	err = json.Unmarshal(recallJSON, &recall)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recall, nil
+}
+
+// GetActiveRecalls returns the recalls that have not been acknowledged by all holders.
+func (s *SmartContract) GetActiveRecalls(ctx contractapi.TransactionContextInterface) ([]*Recall, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(recallObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*Recall
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var recall Recall
+		err = json.Unmarshal(queryResponse.Value, &recall)
+		if err != nil {
+			return nil, err
+		}
+
+		if recall.Status == "active" {
+			result = append(result, &recall)
+		}
+	}
+
+	return result, nil
+}
+
+// lineage returns the id of an asset followed by the ids of all assets derived from it.
+func (s *SmartContract) lineage(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
+	result := []string{id}
+	visited := map[string]bool{id: true}
+
+	for i := 0; i < len(result); i++ {
+		resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(lineageObjectType, []string{result[i]})
+		if err != nil {
+			return nil, err
+		}
+
+		var children []string
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+
+			_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			children = append(children, attributes[1])
+		}
+		resultsIterator.Close()
+
+		sort.Strings(children)
+		for _, child := range children {
+			if !visited[child] {
+				visited[child] = true
+				result = append(result, child)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// recallPenalty returns the penalty of a recall.
+func (s *SmartContract) recallPenalty(ctx contractapi.TransactionContextInterface) (float32, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(recallPenaltyObjectType, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	penaltyJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if penaltyJSON == nil {
+		return defaultRecallPenalty, nil
+	}
+
+	var penalty float32
+	err = json.Unmarshal(penaltyJSON, &penalty)
+	if err != nil {
+		return 0, err
+	}
+
+	return penalty, nil
+}
+
+// validateRecallPenalty checks the penalty of a recall before it is stored.
+func validateRecallPenalty(penalty float32) error {
+	if penalty < 0 {
+		return fmt.Errorf("the penalty must not be negative")
+	}
+
+	return nil
+}
+
+// putRecallPenalty stores the penalty of a recall.
+func (s *SmartContract) putRecallPenalty(ctx contractapi.TransactionContextInterface, penalty float32) error {
+	err := validateRecallPenalty(penalty)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(recallPenaltyObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	penaltyJSON, err := json.Marshal(penalty)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, penaltyJSON)
+}
+
+func (s *SmartContract) putRecall(ctx contractapi.TransactionContextInterface, recall *Recall) error {
+	key, err := ctx.GetStub().CreateCompositeKey(recallObjectType, []string{recall.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	recallJSON, err := json.Marshal(recall)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, recallJSON)
+}
@@ -0,0 +1,91 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecall(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit})
+
+	assetTransfer := chaincode.SmartContract{}
+	for _, id := range []string{"lot1", "pack1", "pack2"} {
+		err = assetTransfer.CreateAsset(transactionContext, id, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+		commit()
+		require.NoError(t, err)
+	}
+	err = assetTransfer.LinkAsset(transactionContext, "pack1", "lot1")
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.LinkAsset(transactionContext, "pack2", "pack1")
+	commit()
+	require.NoError(t, err)
+	_, err = assetTransfer.TransferAsset(transactionContext, "pack2", "org2admin")
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.SetRecallPenalty(transactionContext, 50)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.InitiateRecall(transactionContext, "lot1", "contaminated")
+	commit()
+	require.NoError(t, err)
+
+	name, payload := chaincodeStub.SetEventArgsForCall(0)
+	require.Equal(t, "Recall", name)
+	var recall chaincode.Recall
+	err = json.Unmarshal(payload, &recall)
+	require.NoError(t, err)
+	require.Equal(t, []string{"lot1", "pack1", "pack2"}, recall.Assets)
+	require.Equal(t, map[string]bool{"org1admin": false, "org2admin": false}, recall.Holders)
+
+	pack2, err := assetTransfer.ReadAsset(transactionContext, "pack2")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.AssetRecall{ID: "lot1", Reason: "contaminated"}, pack2.Recall)
+
+	_, err = assetTransfer.TransferAsset(transactionContext, "pack1", "org2admin")
+	commit()
+	require.EqualError(t, err, "the asset pack1 has been recalled: contaminated")
+
+	org1, err := assetTransfer.ReadCredit(transactionContext, "org1admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(2.5), org1.Score, "the recall penalty is deducted from the score")
+	require.Equal(t, float32(3), org1.Transaction, "the recall penalty is not a credit transaction")
+	require.InDelta(t, 2.5/3, org1.FinalScore, 1e-6)
+
+	err = assetTransfer.AcknowledgeRecall(transactionContext, "lot1")
+	commit()
+	require.NoError(t, err)
+	recalls, err := assetTransfer.GetActiveRecalls(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, recalls, 1)
+
+	setCaller(t, chaincodeStub, "org2admin")
+	err = assetTransfer.AcknowledgeRecall(transactionContext, "lot1")
+	commit()
+	require.NoError(t, err)
+	recalls, err = assetTransfer.GetActiveRecalls(transactionContext)
+	require.NoError(t, err)
+	require.Empty(t, recalls)
+
+	pack2, err = assetTransfer.ReadAsset(transactionContext, "pack2")
+	require.NoError(t, err)
+	require.True(t, pack2.Recall.Acknowledged)
+
+	setCaller(t, chaincodeStub, "org5admin")
+	err = assetTransfer.AcknowledgeRecall(transactionContext, "lot1")
+	commit()
+	require.EqualError(t, err, "org5admin holds no asset affected by recall lot1")
+}
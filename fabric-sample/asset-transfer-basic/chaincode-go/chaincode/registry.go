@@ -0,0 +1,471 @@
+package chaincode
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	creditRegistryObjectType = "creditRegistry"
+	creditSourceObjectType   = "creditSource"
+	creditUpdateObjectType   = "creditUpdate"
+	appliedUpdateObjectType  = "appliedUpdate"
+	minimumGradeObjectType   = "minimumGrade"
+)
+
+// grades lists the credit grades from best to worst
+var grades = []string{"A", "B", "C", "D"}
+
+// CreditRegistry is the channel and chaincode that hold the credit registry when it is not kept on this channel
+type CreditRegistry struct {
+	Channel   string `json:"Channel"`
+	Chaincode string `json:"Chaincode"`
+}
+
+// CreditSource is a channel whose queued credit updates the registry accepts, and the chaincode that queues them
+type CreditSource struct {
+	Channel   string `json:"Channel"`
+	Chaincode string `json:"Chaincode"`
+}
+
+// CreditUpdate is a score update queued on a channel whose credit registry is on another channel.
+// Score is added as one more credit transaction, or for a Deduction subtracted from the score without
+// counting a transaction. SignedProposal is the base64 encoded signed proposal of the transaction that queued it.
+type CreditUpdate struct {
+	ID             string    `json:"ID"`
+	Participant    string    `json:"Participant"`
+	Score          float32   `json:"Score"`
+	Deduction      bool      `json:"Deduction,omitempty" metadata:",optional"`
+	Reason         string    `json:"Reason"`
+	Channel        string    `json:"Channel"`
+	Submitter      string    `json:"Submitter"`
+	SignedProposal string    `json:"SignedProposal"`
+	TxID           string    `json:"TxID"`
+	TimeStamp      time.Time `json:"TimeStamp"`
+}
+
+// SetCreditRegistry keeps the credit registry on the given channel and chaincode instead of this channel.
+// Credit is then read through InvokeChaincode and score updates are queued for ApplyCreditUpdate.
+// An empty channel keeps the registry on this channel.
+func (s *SmartContract) SetCreditRegistry(ctx contractapi.TransactionContextInterface, channel string, chaincodeName string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putCreditRegistry(ctx, channel, chaincodeName)
+}
+
+// ReadCreditRegistry returns where the credit registry is kept, or nil when it is on this channel.
+func (s *SmartContract) ReadCreditRegistry(ctx contractapi.TransactionContextInterface) (*CreditRegistry, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(creditRegistryObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	registryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if registryJSON == nil {
+		return nil, nil
+	}
+
+	var registry CreditRegistry
+	err = json.Unmarshal(registryJSON, &registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registry, nil
+}
+
+// SetCreditSource allows or disallows the credit updates queued by a chaincode on another channel.
+func (s *SmartContract) SetCreditSource(ctx contractapi.TransactionContextInterface, channel string, chaincodeName string, allowed bool) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putCreditSource(ctx, channel, chaincodeName, allowed)
+}
+
+// SetMinimumGrade sets the credit grade a new owner must have for an asset to be transferred to it.
+// An empty grade removes the requirement.
+func (s *SmartContract) SetMinimumGrade(ctx contractapi.TransactionContextInterface, grade string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putMinimumGrade(ctx, grade)
+}
+
+// ReadCreditUpdate returns a credit update queued on this channel.
+func (s *SmartContract) ReadCreditUpdate(ctx contractapi.TransactionContextInterface, id string) (*CreditUpdate, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(creditUpdateObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	updateJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if updateJSON == nil {
+		return nil, fmt.Errorf("the credit update %s does not exist", id)
+	}
+
+	var update CreditUpdate
+	err = json.Unmarshal(updateJSON, &update)
+	if err != nil {
+		return nil, err
+	}
+
+	return &update, nil
+}
+
+// GetQueuedCreditUpdates returns all credit updates queued on this channel, for a relay to apply on the registry channel.
+func (s *SmartContract) GetQueuedCreditUpdates(ctx contractapi.TransactionContextInterface) ([]*CreditUpdate, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(creditUpdateObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*CreditUpdate
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var update CreditUpdate
+		err = json.Unmarshal(queryResponse.Value, &update)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &update)
+	}
+
+	return result, nil
+}
+
+// ApplyCreditUpdate applies a credit update queued on another channel to the registry on this channel.
+// Anyone can relay an update: it is read back from the source channel through InvokeChaincode, so only
+// updates committed by an allowed source are applied, and each of them only once.
+func (s *SmartContract) ApplyCreditUpdate(ctx contractapi.TransactionContextInterface, channel string, updateID string) error {
+	registry, err := s.ReadCreditRegistry(ctx)
+	if err != nil {
+		return err
+	}
+	if registry != nil {
+		return fmt.Errorf("the credit registry is kept on channel %s", registry.Channel)
+	}
+
+	source, err := s.readCreditSource(ctx, channel)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return fmt.Errorf("credit updates from channel %s are not allowed", channel)
+	}
+
+	appliedKey, err := ctx.GetStub().CreateCompositeKey(appliedUpdateObjectType, []string{channel, updateID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	applied, err := ctx.GetStub().GetState(appliedKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if applied != nil {
+		return fmt.Errorf("the credit update %s from channel %s has already been applied", updateID, channel)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(source.Chaincode, [][]byte{[]byte("ReadCreditUpdate"), []byte(updateID)}, channel)
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to read credit update %s from channel %s: %s", updateID, channel, response.Message)
+	}
+
+	var update CreditUpdate
+	err = json.Unmarshal(response.Payload, &update)
+	if err != nil {
+		return err
+	}
+	if update.ID != updateID || update.Channel != channel {
+		return fmt.Errorf("the credit update %s does not belong to channel %s", updateID, channel)
+	}
+
+	if update.Deduction {
+		err = s.applyCreditDeduction(ctx, update.Participant, update.Score)
+	} else {
+		err = s.applyCreditScore(ctx, update.Participant, update.Score)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(appliedKey, []byte(ctx.GetStub().GetTxID()))
+}
+
+// updateCredit applies a score to the credit of a participant as one credit transaction, or queues it
+// when the registry is kept on another channel. reason tells apart the updates of one participant within
+// a transaction.
+func (s *SmartContract) updateCredit(ctx contractapi.TransactionContextInterface, id string, score float32, reason string) error {
+	return s.changeCredit(ctx, id, score, false, reason)
+}
+
+// deductCredit deducts a penalty in percentage points from the score of a participant without counting
+// a credit transaction, so that the penalty lowers the participant's final score whatever it was before.
+func (s *SmartContract) deductCredit(ctx contractapi.TransactionContextInterface, id string, penalty float32, reason string) error {
+	return s.changeCredit(ctx, id, penalty/100, true, reason)
+}
+
+// changeCredit applies a score or a deduction to the credit of a participant, or queues it when the
+// registry is kept on another channel.
+func (s *SmartContract) changeCredit(ctx contractapi.TransactionContextInterface, id string, score float32, deduction bool, reason string) error {
+	registry, err := s.ReadCreditRegistry(ctx)
+	if err != nil {
+		return err
+	}
+	if registry == nil {
+		if deduction {
+			return s.applyCreditDeduction(ctx, id, score)
+		}
+		return s.applyCreditScore(ctx, id, score)
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	signedProposal, err := ctx.GetStub().GetSignedProposal()
+	if err != nil {
+		return fmt.Errorf("failed to get the signed proposal: %v", err)
+	}
+	signedProposalBytes, err := proto.Marshal(signedProposal)
+	if err != nil {
+		return err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	update := CreditUpdate{
+		ID:             strings.Join([]string{txID, id, reason}, ":"),
+		Participant:    id,
+		Score:          score,
+		Deduction:      deduction,
+		Reason:         reason,
+		Channel:        ctx.GetStub().GetChannelID(),
+		Submitter:      caller,
+		SignedProposal: base64.StdEncoding.EncodeToString(signedProposalBytes),
+		TxID:           txID,
+		TimeStamp:      timestamp,
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(creditUpdateObjectType, []string{update.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	updateJSON, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, updateJSON)
+}
+
+// remoteCredit reads the credit of a participant from the registry channel. The registry applies its
+// read policy to the original caller, so the result may hold only the grade.
+func (s *SmartContract) remoteCredit(ctx contractapi.TransactionContextInterface, registry *CreditRegistry, id string) (*Credit, error) {
+	response := ctx.GetStub().InvokeChaincode(registry.Chaincode, [][]byte{[]byte("ReadCredit"), []byte(id)}, registry.Channel)
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to read credit %s from channel %s: %s", id, registry.Channel, response.Message)
+	}
+
+	var credit Credit
+	err := json.Unmarshal(response.Payload, &credit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credit, nil
+}
+
+// checkMinimumGrade returns an error if the participant's grade is below the minimum grade for transfers.
+func (s *SmartContract) checkMinimumGrade(ctx contractapi.TransactionContextInterface, participant string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(minimumGradeObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	minimum, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if minimum == nil {
+		return nil
+	}
+
+	credit, err := s.ReadCredit(ctx, participant)
+	if err != nil {
+		return err
+	}
+
+	if gradeRank(credit.Grade) > gradeRank(string(minimum)) {
+		return fmt.Errorf("the credit grade %s of %s is below the minimum grade %s", credit.Grade, participant, minimum)
+	}
+
+	return nil
+}
+
+// gradeRank returns the position of a grade from best to worst; unrated participants rank last.
+func gradeRank(grade string) int {
+	for i, g := range grades {
+		if g == grade {
+			return i
+		}
+	}
+	return len(grades)
+}
+
+// validateCreditRegistry checks where the credit registry is kept before it is stored. An empty
+// channel keeps it on this channel again.
+func (s *SmartContract) validateCreditRegistry(ctx contractapi.TransactionContextInterface, channel string, chaincodeName string) error {
+	if channel == "" {
+		return nil
+	}
+	if channel == ctx.GetStub().GetChannelID() {
+		return fmt.Errorf("the credit registry of channel %s is already kept on it", channel)
+	}
+	if chaincodeName == "" {
+		return fmt.Errorf("the chaincode of the credit registry is required")
+	}
+
+	return nil
+}
+
+// putCreditRegistry stores where the credit registry is kept.
+func (s *SmartContract) putCreditRegistry(ctx contractapi.TransactionContextInterface, channel string, chaincodeName string) error {
+	err := s.validateCreditRegistry(ctx, channel, chaincodeName)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(creditRegistryObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if channel == "" {
+		return ctx.GetStub().DelState(key)
+	}
+
+	registryJSON, err := json.Marshal(CreditRegistry{Channel: channel, Chaincode: chaincodeName})
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, registryJSON)
+}
+
+// validateCreditSource checks a source of credit updates before it is stored.
+func validateCreditSource(chaincodeName string, allowed bool) error {
+	if allowed && chaincodeName == "" {
+		return fmt.Errorf("the chaincode of the credit source is required")
+	}
+
+	return nil
+}
+
+// putCreditSource stores or removes a source of credit updates.
+func (s *SmartContract) putCreditSource(ctx contractapi.TransactionContextInterface, channel string, chaincodeName string, allowed bool) error {
+	err := validateCreditSource(chaincodeName, allowed)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(creditSourceObjectType, []string{channel})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if !allowed {
+		return ctx.GetStub().DelState(key)
+	}
+
+	sourceJSON, err := json.Marshal(CreditSource{Channel: channel, Chaincode: chaincodeName})
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, sourceJSON)
+}
+
+// readCreditSource returns the allowed source of credit updates on a channel, or nil if there is none.
+func (s *SmartContract) readCreditSource(ctx contractapi.TransactionContextInterface, channel string) (*CreditSource, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(creditSourceObjectType, []string{channel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	sourceJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if sourceJSON == nil {
+		return nil, nil
+	}
+
+	var source CreditSource
+	err = json.Unmarshal(sourceJSON, &source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &source, nil
+}
+
+// validateMinimumGrade checks the minimum grade for transfers before it is stored. An empty grade
+// removes the minimum.
+func validateMinimumGrade(grade string) error {
+	if grade != "" && gradeRank(grade) == len(grades) {
+		return fmt.Errorf("unsupported grade %q, expected one of %s", grade, strings.Join(grades, ", "))
+	}
+
+	return nil
+}
+
+// putMinimumGrade stores the minimum grade for transfers.
+func (s *SmartContract) putMinimumGrade(ctx contractapi.TransactionContextInterface, grade string) error {
+	err := validateMinimumGrade(grade)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(minimumGradeObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if grade == "" {
+		return ctx.GetStub().DelState(key)
+	}
+
+	return ctx.GetStub().PutState(key, []byte(grade))
+}
@@ -0,0 +1,123 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteCreditRegistry(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+	commit := setState(chaincodeStub, map[string][]byte{})
+	chaincodeStub.GetChannelIDReturns("products")
+	chaincodeStub.GetTxIDReturns("tx1")
+	chaincodeStub.GetSignedProposalReturns(&peer.SignedProposal{ProposalBytes: []byte("proposal"), Signature: []byte("signature")}, nil)
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.SetCreditRegistry(transactionContext, "products", "credit")
+	commit()
+	require.EqualError(t, err, "the credit registry of channel products is already kept on it")
+	err = assetTransfer.SetCreditRegistry(transactionContext, "consortium", "credit")
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1)
+	commit()
+	require.NoError(t, err)
+
+	updates, err := assetTransfer.GetQueuedCreditUpdates(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, updates, 4)
+	update, err := assetTransfer.ReadCreditUpdate(transactionContext, "tx1:org5admin:asset1")
+	require.NoError(t, err)
+	require.Equal(t, float32(0), update.Score)
+	require.Equal(t, "products", update.Channel)
+	require.NotEmpty(t, update.SignedProposal)
+
+	err = assetTransfer.RegisterParticipant(transactionContext, "org3admin")
+	commit()
+	require.EqualError(t, err, "the credit registry is kept on channel consortium")
+
+	gradeC, err := json.Marshal(&chaincode.Credit{ID: "org2admin", Grade: "C"})
+	require.NoError(t, err)
+	chaincodeStub.InvokeChaincodeReturns(peer.Response{Status: 200, Payload: gradeC})
+
+	credit, err := assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, "C", credit.Grade)
+	name, args, channel := chaincodeStub.InvokeChaincodeArgsForCall(0)
+	require.Equal(t, "credit", name)
+	require.Equal(t, [][]byte{[]byte("ReadCredit"), []byte("org2admin")}, args)
+	require.Equal(t, "consortium", channel)
+
+	err = assetTransfer.SetMinimumGrade(transactionContext, "E")
+	commit()
+	require.EqualError(t, err, `unsupported grade "E", expected one of A, B, C, D`)
+	err = assetTransfer.SetMinimumGrade(transactionContext, "B")
+	commit()
+	require.NoError(t, err)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "org2admin")
+	commit()
+	require.EqualError(t, err, "the credit grade C of org2admin is below the minimum grade B")
+
+	err = assetTransfer.SetMinimumGrade(transactionContext, "C")
+	commit()
+	require.NoError(t, err)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "org2admin")
+	commit()
+	require.NoError(t, err)
+}
+
+func TestApplyCreditUpdate(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+	chaincodeStub.GetChannelIDReturns("consortium")
+
+	credit, err := json.Marshal(&chaincode.Credit{ID: "org5admin"})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org5admin": credit})
+
+	update, err := json.Marshal(&chaincode.CreditUpdate{ID: "tx1:org5admin:asset1", Participant: "org5admin", Score: 0.5, Channel: "products"})
+	require.NoError(t, err)
+	chaincodeStub.InvokeChaincodeReturns(peer.Response{Status: 200, Payload: update})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.ApplyCreditUpdate(transactionContext, "products", "tx1:org5admin:asset1")
+	commit()
+	require.EqualError(t, err, "credit updates from channel products are not allowed")
+
+	err = assetTransfer.SetCreditSource(transactionContext, "products", "basic", true)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.ApplyCreditUpdate(transactionContext, "products", "tx1:org5admin:asset1")
+	commit()
+	require.NoError(t, err)
+	name, _, channel := chaincodeStub.InvokeChaincodeArgsForCall(0)
+	require.Equal(t, "basic", name)
+	require.Equal(t, "products", channel)
+
+	err = assetTransfer.ApplyCreditUpdate(transactionContext, "products", "tx1:org5admin:asset1")
+	commit()
+	require.EqualError(t, err, "the credit update tx1:org5admin:asset1 from channel products has already been applied")
+
+	org5, err := assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(1), org5.Transaction)
+	require.Equal(t, float32(0.5), org5.Score)
+
+	chaincodeStub.InvokeChaincodeReturns(peer.Response{Status: 500, Message: "the credit update tx2 does not exist"})
+	err = assetTransfer.ApplyCreditUpdate(transactionContext, "products", "tx2")
+	commit()
+	require.EqualError(t, err, "failed to read credit update tx2 from channel products: the credit update tx2 does not exist")
+}
@@ -0,0 +1,421 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	slaObjectType        = "sla"
+	overdueObjectType    = "overdue"
+	stageIndexObjectType = "stageIndex"
+)
+
+// StageSLA is the number of hours within which a checkpoint stage must be completed, measured from the
+// completion of the previous stage, and the percentage points deducted from the participant it is
+// scored against when it is overdue. Stages follow the order of the checkpoint table.
+type StageSLA struct {
+	Checkpoint string  `json:"Checkpoint"`
+	Hours      int     `json:"Hours"`
+	Penalty    float32 `json:"Penalty"`
+}
+
+// OverdueStage is a stage of an asset that was not completed by its deadline
+type OverdueStage struct {
+	AssetID     string    `json:"AssetID"`
+	Checkpoint  string    `json:"Checkpoint"`
+	Participant string    `json:"Participant"`
+	Deadline    time.Time `json:"Deadline"`
+	Penalty     float32   `json:"Penalty"`
+	TxID        string    `json:"TxID"`
+	TimeStamp   time.Time `json:"TimeStamp"`
+}
+
+// EnforceDeadlinesResult holds the stages marked overdue in one page of assets and the bookmark of the next page
+type EnforceDeadlinesResult struct {
+	Marked              []*OverdueStage `json:"marked"`
+	FetchedRecordsCount int32           `json:"fetchedRecordsCount"`
+	Bookmark            string          `json:"bookmark"`
+}
+
+// SetStageSLA sets the SLA of a checkpoint stage. An SLA of 0 hours removes it.
+func (s *SmartContract) SetStageSLA(ctx contractapi.TransactionContextInterface, checkpointName string, hours int, penalty float32) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putStageSLA(ctx, checkpointName, hours, penalty)
+}
+
+// GetStageSLAs returns the SLAs of all stages.
+func (s *SmartContract) GetStageSLAs(ctx contractapi.TransactionContextInterface) ([]*StageSLA, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(slaObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*StageSLA
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var sla StageSLA
+		err = json.Unmarshal(queryResponse.Value, &sla)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &sla)
+	}
+
+	return result, nil
+}
+
+// EnforceDeadlines marks the stages of a page of assets that are overdue and deducts the late penalties
+// from the scores of the participants responsible, one deduction per participant that does not count as
+// a credit transaction. Anyone can call it; a stage
+// is marked and penalized only once. Assets are visited through the stage index, which holds the assets
+// with stages left to check. Paginated queries are not available to update transactions, so the bookmark
+// is the id of the next asset to visit and an empty bookmark in the result means all assets were visited.
+func (s *SmartContract) EnforceDeadlines(ctx contractapi.TransactionContextInterface, pageSize int, bookmark string) (*EnforceDeadlinesResult, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("the page size must be positive")
+	}
+
+	slas, err := s.GetStageSLAs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(stageIndexObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	// GetState does not return the transaction's own writes, so each participant's credit is written once
+	var participants []string
+	penalties := make(map[string]float32)
+	reasons := make(map[string][]string)
+
+	result := &EnforceDeadlinesResult{Marked: []*OverdueStage{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		assetID := attributes[0]
+		if assetID < bookmark {
+			continue
+		}
+		if result.FetchedRecordsCount == int32(pageSize) {
+			result.Bookmark = assetID
+			break
+		}
+		result.FetchedRecordsCount++
+
+		asset, err := s.readStageIndexAsset(ctx, queryResponse.Key, assetID)
+		if err != nil {
+			return nil, err
+		}
+		if asset == nil {
+			continue
+		}
+
+		for _, sla := range slas {
+			overdue, err := s.overdueStage(ctx, asset, sla, timestamp)
+			if err != nil {
+				return nil, err
+			}
+			if overdue == nil {
+				continue
+			}
+
+			if _, ok := penalties[overdue.Participant]; !ok {
+				participants = append(participants, overdue.Participant)
+			}
+			penalties[overdue.Participant] += overdue.Penalty
+			reasons[overdue.Participant] = append(reasons[overdue.Participant], overdue.AssetID+"/"+overdue.Checkpoint)
+
+			err = s.putOverdueStage(ctx, overdue)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Marked = append(result.Marked, overdue)
+		}
+	}
+
+	for _, participant := range participants {
+		err = s.deductCredit(ctx, participant, penalties[participant], strings.Join(reasons[participant], ","))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// CompleteStage records the result of a pending stage of an asset and scores it. The stage is completed
+// at the time of this transaction, which the SLAs of the following stages are measured from. Only the
+// owner of the asset can complete its stages; dual inspected stages are decided through ReportInspection.
+func (s *SmartContract) CompleteStage(ctx contractapi.TransactionContextInterface, assetID string, checkpointName string, result InspectionResult) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+	if asset.Owner != caller {
+		return fmt.Errorf("only the owner of the asset can complete its stages")
+	}
+
+	c, err := findCheckpoint(checkpointName)
+	if err != nil {
+		return err
+	}
+	if !pending(asset, c.Name) {
+		return fmt.Errorf("the stage %s of asset %s is not pending", c.Name, asset.ID)
+	}
+
+	dual, err := s.readDualInspection(ctx, c.Name)
+	if err != nil {
+		return err
+	}
+	if dual != nil {
+		return fmt.Errorf("the stage %s requires dual inspection, its result is decided through ReportInspection", c.Name)
+	}
+
+	err = applyInspectionResults(asset, []string{c.Name}, map[string]InspectionResult{c.Name: result}, false)
+	if err != nil {
+		return err
+	}
+
+	err = s.scoreCheckpoints(ctx, caller, asset, []string{c.Name})
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+	asset.TimeStamp = timestamp
+	asset.Sender = caller
+	asset.Function = "CompleteStage"
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(asset.ID, assetJSON)
+}
+
+// readStageIndexAsset returns the asset of a stage index entry. It removes the entry and returns nil
+// once the asset has been deleted or has no stage left to check, as no stage of it can become overdue.
+func (s *SmartContract) readStageIndexAsset(ctx contractapi.TransactionContextInterface, indexKey string, assetID string) (*Asset, error) {
+	assetJSON, err := ctx.GetStub().GetState(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	var asset *Asset
+	if assetJSON != nil {
+		asset = &Asset{}
+		err = json.Unmarshal(assetJSON, asset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range checkpoints {
+			if _, ok := asset.CheckedAt[c.Name]; !ok && !notApplicable(asset, c.Name) {
+				return asset, nil
+			}
+		}
+	}
+
+	return nil, ctx.GetStub().DelState(indexKey)
+}
+
+// putStageIndex adds an asset to the stage index that EnforceDeadlines visits.
+func (s *SmartContract) putStageIndex(ctx contractapi.TransactionContextInterface, assetID string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(stageIndexObjectType, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, []byte{0x00})
+}
+
+// GetOverdueStages returns the stages marked overdue, of all participants or only the given one.
+// Participants only see their own stages, the consortium admins those of every participant.
+func (s *SmartContract) GetOverdueStages(ctx contractapi.TransactionContextInterface, participant string) ([]*OverdueStage, error) {
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.isConsortiumAdmin(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	if participant != "" && participant != caller && !admin {
+		return nil, fmt.Errorf("only %s and the consortium admins can read its overdue stages", participant)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(overdueObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*OverdueStage
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var overdue OverdueStage
+		err = json.Unmarshal(queryResponse.Value, &overdue)
+		if err != nil {
+			return nil, err
+		}
+
+		if participant != "" && overdue.Participant != participant {
+			continue
+		}
+		if !admin && overdue.Participant != caller {
+			continue
+		}
+		result = append(result, &overdue)
+	}
+
+	return result, nil
+}
+
+// validateStageSLA checks the SLA of a stage before it is stored.
+func validateStageSLA(checkpointName string, hours int, penalty float32) error {
+	_, err := findCheckpoint(checkpointName)
+	if err != nil {
+		return err
+	}
+
+	if hours < 0 || penalty < 0 {
+		return fmt.Errorf("the hours and penalty of an SLA must not be negative")
+	}
+
+	return nil
+}
+
+// putStageSLA stores the SLA of a stage.
+func (s *SmartContract) putStageSLA(ctx contractapi.TransactionContextInterface, checkpointName string, hours int, penalty float32) error {
+	err := validateStageSLA(checkpointName, hours, penalty)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(slaObjectType, []string{checkpointName})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if hours == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+
+	slaJSON, err := json.Marshal(StageSLA{Checkpoint: checkpointName, Hours: hours, Penalty: penalty})
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, slaJSON)
+}
+
+// overdueStage returns the stage of an asset if it is overdue and has not been marked yet. A stage is
+// overdue when it has not been checked and the SLA has passed since the closest earlier stage was checked.
+func (s *SmartContract) overdueStage(ctx contractapi.TransactionContextInterface, asset *Asset, sla *StageSLA, timestamp time.Time) (*OverdueStage, error) {
+	if _, ok := asset.CheckedAt[sla.Checkpoint]; ok || notApplicable(asset, sla.Checkpoint) {
+		return nil, nil
+	}
+
+	var start time.Time
+	for _, c := range checkpoints {
+		if c.Name == sla.Checkpoint {
+			break
+		}
+		if checkedAt, ok := asset.CheckedAt[c.Name]; ok {
+			start = checkedAt
+		}
+	}
+	if start.IsZero() {
+		return nil, nil
+	}
+
+	deadline := start.Add(time.Duration(sla.Hours) * time.Hour)
+	if !timestamp.After(deadline) {
+		return nil, nil
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(overdueObjectType, []string{asset.ID, sla.Checkpoint})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	overdueJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if overdueJSON != nil {
+		return nil, nil
+	}
+
+	c, err := findCheckpoint(sla.Checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OverdueStage{
+		AssetID:     asset.ID,
+		Checkpoint:  sla.Checkpoint,
+		Participant: c.participant(asset),
+		Deadline:    deadline,
+		Penalty:     sla.Penalty,
+		TxID:        ctx.GetStub().GetTxID(),
+		TimeStamp:   timestamp,
+	}, nil
+}
+
+func (s *SmartContract) putOverdueStage(ctx contractapi.TransactionContextInterface, overdue *OverdueStage) error {
+	key, err := ctx.GetStub().CreateCompositeKey(overdueObjectType, []string{overdue.AssetID, overdue.Checkpoint})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	overdueJSON, err := json.Marshal(overdue)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, overdueJSON)
+}
@@ -0,0 +1,204 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEnforceDeadlines(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetDualInspection(transactionContext, "B01", "org3admin")
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetStageSLA(transactionContext, "B01", 48, 50)
+	commit()
+	require.NoError(t, err)
+	for _, name := range []string{"A03", "A04"} {
+		err = assetTransfer.SetDualInspection(transactionContext, name, "org3admin")
+		commit()
+		require.NoError(t, err)
+		err = assetTransfer.SetStageSLA(transactionContext, name, 48, 20)
+		commit()
+		require.NoError(t, err)
+	}
+	err = assetTransfer.SetStageSLA(transactionContext, "Z99", 48, 50)
+	commit()
+	require.EqualError(t, err, "the checkpoint Z99 does not exist")
+
+	for _, id := range []string{"asset1", "asset2"} {
+		err = assetTransfer.CreateAsset(transactionContext, id, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+		commit()
+		require.NoError(t, err)
+	}
+
+	setCaller(t, chaincodeStub, "org2admin")
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 16, 0, 0, 0, 0, time.UTC)), nil)
+	result, err := assetTransfer.EnforceDeadlines(transactionContext, 10, "")
+	commit()
+	require.NoError(t, err)
+	require.Empty(t, result.Marked, "B01 is still within its SLA")
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 18, 0, 0, 0, 0, time.UTC)), nil)
+	result, err = assetTransfer.EnforceDeadlines(transactionContext, 1, "")
+	commit()
+	require.NoError(t, err)
+	require.Len(t, result.Marked, 3)
+	require.Equal(t, "asset1", result.Marked[2].AssetID)
+	require.Equal(t, "org5admin", result.Marked[2].Participant)
+	require.True(t, result.Marked[2].Deadline.Equal(time.Date(2023, 3, 17, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, "asset2", result.Bookmark)
+
+	result, err = assetTransfer.EnforceDeadlines(transactionContext, 10, result.Bookmark)
+	commit()
+	require.NoError(t, err)
+	require.Len(t, result.Marked, 3)
+	require.Equal(t, "asset2", result.Marked[0].AssetID)
+	require.Equal(t, "", result.Bookmark)
+
+	result, err = assetTransfer.EnforceDeadlines(transactionContext, 10, "")
+	commit()
+	require.NoError(t, err)
+	require.Empty(t, result.Marked, "overdue stages are penalized once")
+	require.Equal(t, int32(2), result.FetchedRecordsCount, "only assets with stages left to check are visited")
+
+	overdue, err := assetTransfer.GetOverdueStages(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Len(t, overdue, 4)
+	_, err = assetTransfer.GetOverdueStages(transactionContext, "org5admin")
+	require.EqualError(t, err, "only org5admin and the consortium admins can read its overdue stages")
+	overdue, err = assetTransfer.GetOverdueStages(transactionContext, "")
+	require.NoError(t, err)
+	require.Len(t, overdue, 4, "participants only see their own stages")
+
+	setCaller(t, chaincodeStub, "org1admin")
+	overdue, err = assetTransfer.GetOverdueStages(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Len(t, overdue, 2)
+	overdue, err = assetTransfer.GetOverdueStages(transactionContext, "org5admin1")
+	require.NoError(t, err)
+	require.Empty(t, overdue)
+	overdue, err = assetTransfer.GetOverdueStages(transactionContext, "")
+	require.NoError(t, err)
+	require.Len(t, overdue, 6)
+
+	org5, err := assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(0), org5.Transaction, "late penalties are not credit transactions")
+	require.InDelta(t, -1, org5.Score, 1e-6)
+
+	org2, err := assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(2), org2.Transaction, "only the checkpoints scored at creation count as transactions")
+	require.InDelta(t, 1.2, org2.Score, 1e-6, "each asset deducts both late penalties")
+	require.InDelta(t, 0.6, org2.FinalScore, 1e-6)
+}
+
+func TestCompleteStage(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	lowScore, err := json.Marshal(&chaincode.Credit{ID: "org2admin", Transaction: 2, Score: 1, FinalScore: 0.5})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": lowScore, "org5admin": credit, "org5admin1": credit})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetStageSLA(transactionContext, "A02", 24, 10)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetStageSLA(transactionContext, "B01", 24, 10)
+	commit()
+	require.NoError(t, err)
+
+	_, err = assetTransfer.CreateAssetFromInspection(transactionContext, chaincode.InspectionInput{
+		ID:                      "asset1",
+		AcceptanceSampling:      chaincode.ResultPass,
+		ManufacturingEquipment:  chaincode.ResultPass,
+		TransportationEquipment: chaincode.ResultPass,
+		InventoryManagement:     chaincode.ResultPass,
+		SaveEquipment:           chaincode.ResultPass,
+		A01:                     chaincode.ResultPending,
+		A02:                     chaincode.ResultPending,
+		A03:                     chaincode.ResultNotApplicable,
+		A04:                     chaincode.ResultNotApplicable,
+		B01:                     chaincode.ResultPending,
+		C01:                     chaincode.ResultNotApplicable,
+	})
+	commit()
+	require.NoError(t, err)
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, -2, asset.A01)
+	require.NotContains(t, asset.CheckedAt, "A01", "pending stages are not completed at creation")
+	org2, err := assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(2), org2.Transaction, "pending stages are not scored")
+
+	// A01 is completed two days after creation; the SLA of A02 runs from then
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 17, 0, 0, 0, 0, time.UTC)), nil)
+	err = assetTransfer.CompleteStage(transactionContext, "asset1", "A01", chaincode.ResultPass)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.CompleteStage(transactionContext, "asset1", "A01", chaincode.ResultFail)
+	commit()
+	require.EqualError(t, err, "the stage A01 of asset asset1 is not pending")
+	err = assetTransfer.CompleteStage(transactionContext, "asset1", "A02", chaincode.ResultPending)
+	commit()
+	require.EqualError(t, err, `invalid inspection: A02: expected pass, fail or not_applicable, got "pending"`)
+
+	asset, err = assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, 1, asset.A01)
+	require.True(t, asset.CheckedAt["A01"].Equal(time.Date(2023, 3, 17, 0, 0, 0, 0, time.UTC)))
+	org2, err = assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(3), org2.Transaction)
+	require.InDelta(t, 2.0/3, org2.FinalScore, 1e-6)
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 17, 12, 0, 0, 0, time.UTC)), nil)
+	result, err := assetTransfer.EnforceDeadlines(transactionContext, 10, "")
+	commit()
+	require.NoError(t, err)
+	require.Empty(t, result.Marked, "A02 and B01 are due a day after A01 was completed")
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 18, 12, 0, 0, 0, time.UTC)), nil)
+	result, err = assetTransfer.EnforceDeadlines(transactionContext, 10, "")
+	commit()
+	require.NoError(t, err)
+	require.Len(t, result.Marked, 2)
+	require.Equal(t, "A02", result.Marked[0].Checkpoint)
+	require.True(t, result.Marked[0].Deadline.Equal(time.Date(2023, 3, 18, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, "B01", result.Marked[1].Checkpoint)
+
+	org2, err = assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(3), org2.Transaction, "the late penalty is not a credit transaction")
+	require.InDelta(t, 1.9/3, org2.FinalScore, 1e-6, "the late penalty lowers the final score")
+	require.Less(t, org2.FinalScore, float32(2.0/3))
+
+	setCaller(t, chaincodeStub, "org2admin")
+	err = assetTransfer.CompleteStage(transactionContext, "asset1", "A02", chaincode.ResultPass)
+	commit()
+	require.EqualError(t, err, "only the owner of the asset can complete its stages")
+}
@@ -20,30 +20,36 @@ type SmartContract struct {
 // Insert struct field in alphabetic order => to achieve determinism across languages
 // golang keeps the order when marshal to json but doesn't order automatically
 type Asset struct {
-	ID                      string    `json:"ID"`
-	Owner                   string    `json:"Owner"`
-	AcceptanceSampling      int       `json:"AcceptanceSampling"`
-	ManufacturingEquipment  int       `json:"ManufacturingEquipment"`
-	TransportationEquipment int       `json:"TransportationEquipment"`
-	InventoryManagement     int       `json:"InventoryManagement"`
-	SaveEquipment           int       `json:"SaveEquipment"`
-	A01                     int       `json:"A01"`
-	A02                     int       `json:"A02"`
-	A03                     int       `json:"A03"`
-	A04                     int       `json:"A04"`
-	B01                     int       `json:"B01"`
-	C01                     int       `json:"C01"`
-	Source                  string    `json:"Source"`
-	TimeStamp               time.Time `json:"TimeStamp"`
-	Sender                  string    `json:"Sender"`
-	Function                string    `json:"Function"`
+	ID                      string               `json:"ID"`
+	Owner                   string               `json:"Owner"`
+	Category                string               `json:"Category"`
+	AcceptanceSampling      int                  `json:"AcceptanceSampling"`
+	ManufacturingEquipment  int                  `json:"ManufacturingEquipment"`
+	TransportationEquipment int                  `json:"TransportationEquipment"`
+	InventoryManagement     int                  `json:"InventoryManagement"`
+	SaveEquipment           int                  `json:"SaveEquipment"`
+	A01                     int                  `json:"A01"`
+	A02                     int                  `json:"A02"`
+	A03                     int                  `json:"A03"`
+	A04                     int                  `json:"A04"`
+	B01                     int                  `json:"B01"`
+	C01                     int                  `json:"C01"`
+	Source                  string               `json:"Source"`
+	CheckedAt               map[string]time.Time `json:"CheckedAt"`
+	Recall                  *AssetRecall         `json:"Recall,omitempty" metadata:",optional"`
+	TimeStamp               time.Time            `json:"TimeStamp"`
+	Sender                  string               `json:"Sender"`
+	Function                string               `json:"Function"`
 }
 
+// Credit is the credit record of a participant. Grade is derived from FinalScore when the record is read;
+// callers that may only see the grade get a record with the other fields left empty.
 type Credit struct {
 	ID          string  `json:"ID"`
 	Transaction float32 `json:"Transaction"`
 	Score       float32 `json:"Score"`
 	FinalScore  float32 `json:"FinalScore"`
+	Grade       string  `json:"Grade,omitempty" metadata:",optional"`
 }
 
 // InitLedger adds a base set of assets to the ledger
@@ -124,112 +130,78 @@ func (s *SmartContract) GetTimeStamp(ctx contractapi.TransactionContextInterface
 	return time.Unix(timestamp.Seconds, int64(timestamp.GetNanos())), nil
 }
 
-// CreateAsset issues a new asset to the world state with given details.
+// CreateAsset issues a new asset to the world state with given details. Each result must be 1 for a
+// pass or 0 for a failure; CreateAssetFromInspection also accepts checkpoints that do not apply.
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, acceptancesampling int, manufacturingequipment int, transportationequipment int, inventorymanagement int, saveequipment int, a01 int, a02 int, a03 int, a04 int, b01 int, c01 int) error {
-	exists, err := s.AssetExists(ctx, id)
+	err := validateResults(checkpointNames(), []int{acceptancesampling, manufacturingequipment, transportationequipment, inventorymanagement, saveequipment, a01, a02, a03, a04, b01, c01})
 	if err != nil {
 		return err
 	}
-	if exists {
-		return fmt.Errorf("the asset %s already exists", id)
-	}
 
-	caller, err := s.GetCallerName(ctx)
-	if err != nil {
-		return err
+	asset := Asset{
+		ID:                      id,
+		AcceptanceSampling:      acceptancesampling,
+		ManufacturingEquipment:  manufacturingequipment,
+		TransportationEquipment: transportationequipment,
+		InventoryManagement:     inventorymanagement,
+		SaveEquipment:           saveequipment,
+		A01:                     a01,
+		A02:                     a02,
+		A03:                     a03,
+		A04:                     a04,
+		B01:                     b01,
+		C01:                     c01,
 	}
 
-	if caller != "org1admin" {
-		return fmt.Errorf("only admin from org1 can use this method")
-	}
+	return s.createAsset(ctx, &asset)
+}
 
-	timestamp, err := s.GetTimeStamp(ctx)
+// createAsset issues an asset holding the given id, category and checkpoint results, and scores it.
+func (s *SmartContract) createAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	exists, err := s.AssetExists(ctx, asset.ID)
 	if err != nil {
 		return err
 	}
-
-	var score float32 = 1
-	if acceptancesampling == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if manufacturingequipment == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if transportationequipment == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if inventorymanagement == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if saveequipment == 0 {
-		score = (score*10 - 2) / 10
+	if exists {
+		return fmt.Errorf("the asset %s already exists", asset.ID)
 	}
 
-	err = s.UpdateCredit(ctx, caller, score)
+	caller, err := s.GetCallerName(ctx)
 	if err != nil {
 		return err
 	}
 
-	score = 1
-	if a01 == 0 {
-		score = (score*100 - 25) / 100
-	}
-	if a02 == 0 {
-		score = (score*100 - 25) / 100
-	}
-	if a03 == 0 {
-		score = (score*100 - 25) / 100
-	}
-	if a04 == 0 {
-		score = (score*100 - 25) / 100
+	if caller != "org1admin" {
+		return fmt.Errorf("only admin from org1 can use this method")
 	}
-	err = s.UpdateCredit(ctx, "org2admin", score)
+
+	timestamp, err := s.GetTimeStamp(ctx)
 	if err != nil {
 		return err
 	}
 
-	score = 1
-	if b01 == 0 {
-		score = (score*10 - 10) / 10
-	}
-	err = s.UpdateCredit(ctx, "org5admin", score)
+	asset.Owner = caller
+	asset.Source = caller
+	asset.TimeStamp = timestamp
+	asset.Sender = caller
+	asset.Function = "CreateAsset"
+
+	err = s.scoreCheckpoints(ctx, caller, asset, checkpointNames())
 	if err != nil {
 		return err
 	}
 
-	score = 1
-	if c01 == 0 {
-		score = (score*10 - 10) / 10
-	}
-	err = s.UpdateCredit(ctx, "org5admin1", score)
+	err = s.putStageIndex(ctx, asset.ID)
 	if err != nil {
 		return err
 	}
 
-	asset := Asset{
-		ID:                      id,
-		Owner:                   caller,
-		AcceptanceSampling:      acceptancesampling,
-		ManufacturingEquipment:  manufacturingequipment,
-		TransportationEquipment: transportationequipment,
-		InventoryManagement:     inventorymanagement,
-		SaveEquipment:           saveequipment,
-		A01:                     a01,
-		A02:                     a02,
-		A03:                     a03,
-		A04:                     a04,
-		B01:                     b01,
-		C01:                     c01,
-		TimeStamp:               timestamp,
-		Sender:                  caller,
-		Function:                "CreateAsset",
-	}
 	assetJSON, err := json.Marshal(asset)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, assetJSON)
+	return ctx.GetStub().PutState(asset.ID, assetJSON)
 }
 
 // ReadAsset returns the asset stored in the world state with given id.
@@ -251,7 +223,36 @@ func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, i
 	return &asset, nil
 }
 
+// ReadCredit returns the credit record of a participant. The participant itself and the consortium
+// admins see the full record, other members only its grade. When the registry is kept on another
+// channel the record is read from there.
 func (s *SmartContract) ReadCredit(ctx contractapi.TransactionContextInterface, id string) (*Credit, error) {
+	registry, err := s.ReadCreditRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if registry != nil {
+		return s.remoteCredit(ctx, registry, id)
+	}
+
+	credit, err := s.readCredit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	full, err := s.canViewCredit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !full {
+		return &Credit{ID: credit.ID, Grade: credit.Grade}, nil
+	}
+
+	return credit, nil
+}
+
+// readCredit returns the full credit record of a participant without any access check.
+func (s *SmartContract) readCredit(ctx contractapi.TransactionContextInterface, id string) (*Credit, error) {
 	creditJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
@@ -265,12 +266,33 @@ func (s *SmartContract) ReadCredit(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return nil, err
 	}
+	credit.Grade = grade(&credit)
 
 	return &credit, nil
 }
 
-// UpdateAsset updates an existing asset in the world state with provided parameters.
+// UpdateAsset updates an existing asset in the world state with provided parameters. Each result must
+// be 1 for a pass or 0 for a failure.
 func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, acceptancesampling int, manufacturingequipment int, transportationequipment int, inventorymanagement int, saveequipment int) error {
+	err := validateResults(sourceCheckpoints, []int{acceptancesampling, manufacturingequipment, transportationequipment, inventorymanagement, saveequipment})
+	if err != nil {
+		return err
+	}
+
+	return s.updateAsset(ctx, &Asset{
+		ID:                      id,
+		AcceptanceSampling:      acceptancesampling,
+		ManufacturingEquipment:  manufacturingequipment,
+		TransportationEquipment: transportationequipment,
+		InventoryManagement:     inventorymanagement,
+		SaveEquipment:           saveequipment,
+	})
+}
+
+// updateAsset replaces the source checkpoint results of an existing asset with those of update and scores them.
+func (s *SmartContract) updateAsset(ctx contractapi.TransactionContextInterface, update *Asset) error {
+	id := update.ID
+
 	exists, err := s.AssetExists(ctx, id)
 	if err != nil {
 		return err
@@ -296,52 +318,51 @@ func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("only admin from org1 can use this function")
 	}
 
-	var score float32 = 1
-	if acceptancesampling == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if manufacturingequipment == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if transportationequipment == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if inventorymanagement == 0 {
-		score = (score*10 - 2) / 10
-	}
-	if saveequipment == 0 {
-		score = (score*10 - 2) / 10
-	}
-
 	timestamp, err := s.GetTimeStamp(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = s.UpdateCredit(ctx, caller, score)
-	if err != nil {
-		return err
-	}
-
 	// overwriting original asset with new asset
 	assetNew := Asset{
 		ID:                      id,
 		Owner:                   caller,
-		AcceptanceSampling:      acceptancesampling,
-		ManufacturingEquipment:  manufacturingequipment,
-		TransportationEquipment: transportationequipment,
-		InventoryManagement:     inventorymanagement,
-		SaveEquipment:           saveequipment,
+		Category:                asset.Category,
+		AcceptanceSampling:      update.AcceptanceSampling,
+		ManufacturingEquipment:  update.ManufacturingEquipment,
+		TransportationEquipment: update.TransportationEquipment,
+		InventoryManagement:     update.InventoryManagement,
+		SaveEquipment:           update.SaveEquipment,
 		A01:                     asset.A01,
 		A02:                     asset.A02,
 		A03:                     asset.A03,
 		A04:                     asset.A04,
 		B01:                     asset.B01,
 		C01:                     asset.C01,
+		Source:                  asset.Source,
+		CheckedAt:               asset.CheckedAt,
+		Recall:                  asset.Recall,
 		TimeStamp:               timestamp,
 		Sender:                  caller,
 		Function:                "Org1UpdateAsset",
 	}
+
+	// results of dual inspected checkpoints can only change through ReportInspection
+	for _, name := range sourceCheckpoints {
+		dual, err := s.readDualInspection(ctx, name)
+		if err != nil {
+			return err
+		}
+		if dual != nil {
+			setCheckpointResult(&assetNew, name, checkpointResult(asset, name))
+		}
+	}
+
+	err = s.scoreCheckpoints(ctx, caller, &assetNew, sourceCheckpoints)
+	if err != nil {
+		return err
+	}
+
 	assetJSON, err := json.Marshal(assetNew)
 	if err != nil {
 		return err
@@ -350,8 +371,20 @@ func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface,
 	return ctx.GetStub().PutState(id, assetJSON)
 }
 
+// UpdateCredit applies a manual score to the credit of a participant. Only an admin of a scoring
+// organization, or the admin of org1 until a governance has been set up, may adjust credit directly.
 func (s *SmartContract) UpdateCredit(ctx contractapi.TransactionContextInterface, id string, score float32) error {
-	credit, err := s.ReadCredit(ctx, id)
+	err := s.checkScoringAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.updateCredit(ctx, id, score, "UpdateCredit")
+}
+
+// applyCreditScore adds a score to the credit record of a participant kept on this channel.
+func (s *SmartContract) applyCreditScore(ctx contractapi.TransactionContextInterface, id string, score float32) error {
+	credit, err := s.readCredit(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -359,6 +392,7 @@ func (s *SmartContract) UpdateCredit(ctx contractapi.TransactionContextInterface
 	credit.Score += score
 	credit.Transaction += 1
 	credit.FinalScore = credit.Score / credit.Transaction
+	credit.Grade = ""
 
 	creditJSON, err := json.Marshal(credit)
 	if err != nil {
@@ -368,6 +402,83 @@ func (s *SmartContract) UpdateCredit(ctx contractapi.TransactionContextInterface
 	return ctx.GetStub().PutState(id, creditJSON)
 }
 
+// applyCreditDeduction subtracts a deduction from the score of a participant kept on this channel
+// without counting a credit transaction. A participant without transactions has its final score
+// lowered as if it had one.
+func (s *SmartContract) applyCreditDeduction(ctx contractapi.TransactionContextInterface, id string, deduction float32) error {
+	credit, err := s.readCredit(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	transactions := credit.Transaction
+	if transactions < 1 {
+		transactions = 1
+	}
+	credit.Score -= deduction
+	credit.FinalScore = credit.Score / transactions
+	credit.Grade = ""
+
+	creditJSON, err := json.Marshal(credit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(id, creditJSON)
+}
+
+// RegisterParticipant adds a participant with an empty credit record to the credit registry.
+func (s *SmartContract) RegisterParticipant(ctx contractapi.TransactionContextInterface, id string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putParticipant(ctx, id)
+}
+
+// validateParticipant checks that a new participant can be registered on this channel.
+func (s *SmartContract) validateParticipant(ctx contractapi.TransactionContextInterface, id string) error {
+	registry, err := s.ReadCreditRegistry(ctx)
+	if err != nil {
+		return err
+	}
+	if registry != nil {
+		return fmt.Errorf("the credit registry is kept on channel %s", registry.Channel)
+	}
+
+	creditJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if creditJSON != nil {
+		return fmt.Errorf("the credit %s already exists", id)
+	}
+
+	return nil
+}
+
+// putParticipant creates the credit record of a new participant, endorsed by the scoring organizations.
+func (s *SmartContract) putParticipant(ctx contractapi.TransactionContextInterface, id string) error {
+	err := s.validateParticipant(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	credit := Credit{ID: id, Transaction: 0, Score: 0, FinalScore: 0}
+	creditJSON, err := json.Marshal(credit)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(id, creditJSON)
+	if err != nil {
+		return err
+	}
+
+	return s.protectKey(ctx, id)
+}
+
 // DeleteAsset deletes an given asset from the world state.
 func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
 	exists, err := s.AssetExists(ctx, id)
@@ -424,11 +535,30 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return "", fmt.Errorf("only the owner of this assets can do the transfer, now the owner is: %v", caller)
 	}
 
+	err = s.checkTrustThreshold(ctx, oldOwner, newOwner)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.checkMinimumGrade(ctx, newOwner)
+	if err != nil {
+		return "", err
+	}
+
 	timestamp, err := s.GetTimeStamp(ctx)
 	if err != nil {
 		return "", err
 	}
 
+	err = s.checkValidity(ctx, asset, timestamp)
+	if err != nil {
+		return "", err
+	}
+
+	if asset.Recall != nil {
+		return "", fmt.Errorf("the asset %s has been recalled: %s", id, asset.Recall.Reason)
+	}
+
 	asset.Owner = newOwner
 	asset.Function = "TransferAsset"
 	asset.Sender = caller
@@ -447,6 +577,40 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 	return oldOwner, nil
 }
 
+// SetAssetCategory sets the product category of an asset, used to group checkpoint statistics.
+func (s *SmartContract) SetAssetCategory(ctx contractapi.TransactionContextInterface, id string, category string) error {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	if asset.Owner != caller {
+		return fmt.Errorf("only the owner of the asset can set its category")
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	asset.Category = category
+	asset.Function = "SetAssetCategory"
+	asset.Sender = caller
+	asset.TimeStamp = timestamp
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(id, assetJSON)
+}
+
 func (s *SmartContract) GetHistoryForKey(ctx contractapi.TransactionContextInterface, id string) ([]*Asset, error) {
 	exists, err := s.AssetExists(ctx, id)
 	if err != nil {
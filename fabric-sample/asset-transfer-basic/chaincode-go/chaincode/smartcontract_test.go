@@ -1,16 +1,31 @@
 package chaincode_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
 	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 //go:generate counterfeiter -o mocks/transaction.go -fake-name TransactionContext . transactionContext
@@ -28,10 +43,141 @@ type stateQueryIterator interface {
 	shim.StateQueryIteratorInterface
 }
 
+// setCaller makes the stub report a creator certificate with the given common name and a fixed transaction timestamp.
+func setCaller(t *testing.T, chaincodeStub *mocks.ChaincodeStub, commonName string) {
+	setCallerWithMSP(t, chaincodeStub, commonName, "Org1MSP")
+}
+
+// setCallerWithMSP is like setCaller with the creator belonging to the given MSP and organizational units.
+func setCallerWithMSP(t *testing.T, chaincodeStub *mocks.ChaincodeStub, commonName string, mspID string, organizationalUnits ...string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName, OrganizationalUnit: organizationalUnits},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})})
+	require.NoError(t, err)
+
+	chaincodeStub.GetCreatorReturns(creator, nil)
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC)), nil)
+}
+
+// useClientIdentity makes the transaction context derive its client identity from the stub's creator.
+func useClientIdentity(t *testing.T, transactionContext *mocks.TransactionContext, chaincodeStub *mocks.ChaincodeStub) {
+	transactionContext.GetClientIdentityCalls(func() cid.ClientIdentity {
+		clientIdentity, err := cid.New(chaincodeStub)
+		require.NoError(t, err)
+		return clientIdentity
+	})
+}
+
+// setState makes the stub serve GetState and queries from the given map and record PutState and DelState
+// into it. Like a peer, the stub only serves the state committed before the transaction, not the
+// transaction's own writes: call the returned commit function between transactions.
+func setState(chaincodeStub *mocks.ChaincodeStub, state map[string][]byte) (commit func()) {
+	committed := make(map[string][]byte)
+	commit = func() {
+		committed = make(map[string][]byte, len(state))
+		for key, value := range state {
+			committed[key] = value
+		}
+	}
+	commit()
+
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		return committed[key], nil
+	})
+	chaincodeStub.PutStateCalls(func(key string, value []byte) error {
+		state[key] = value
+		return nil
+	})
+	chaincodeStub.DelStateCalls(func(key string) error {
+		delete(state, key)
+		return nil
+	})
+	chaincodeStub.CreateCompositeKeyCalls(shim.CreateCompositeKey)
+	chaincodeStub.SplitCompositeKeyCalls(func(key string) (string, []string, error) {
+		components := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "\x00"), "\x00"), "\x00")
+		return components[0], components[1:], nil
+	})
+	chaincodeStub.GetStateByPartialCompositeKeyCalls(func(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+		prefix, err := shim.CreateCompositeKey(objectType, attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []string
+		for key := range committed {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		return newStateQueryIterator(committed, keys), nil
+	})
+	chaincodeStub.GetStateByPartialCompositeKeyWithPaginationCalls(func(objectType string, attributes []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+		prefix, err := shim.CreateCompositeKey(objectType, attributes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var keys []string
+		for key := range committed {
+			if strings.HasPrefix(key, prefix) && key >= bookmark {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		// like the peer, the bookmark is the key the next page starts at
+		metadata := &peer.QueryResponseMetadata{}
+		if len(keys) > int(pageSize) {
+			metadata.Bookmark = keys[pageSize]
+			keys = keys[:pageSize]
+		}
+		metadata.FetchedRecordsCount = int32(len(keys))
+
+		return newStateQueryIterator(committed, keys), metadata, nil
+	})
+	chaincodeStub.GetStateByRangeCalls(func(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+		var keys []string
+		for key := range committed {
+			if !strings.HasPrefix(key, "\x00") && key >= startKey && (endKey == "" || key < endKey) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		return newStateQueryIterator(committed, keys), nil
+	})
+
+	return commit
+}
+
+// newStateQueryIterator returns an iterator over the given keys of the state.
+func newStateQueryIterator(state map[string][]byte, keys []string) *mocks.StateQueryIterator {
+	iterator := &mocks.StateQueryIterator{}
+	for i, key := range keys {
+		iterator.HasNextReturnsOnCall(i, true)
+		iterator.NextReturnsOnCall(i, &queryresult.KV{Key: key, Value: state[key]}, nil)
+	}
+	return iterator
+}
+
 func TestInitLedger(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
 
 	assetTransfer := chaincode.SmartContract{}
 	err := assetTransfer.InitLedger(transactionContext)
@@ -46,17 +192,28 @@ func TestCreateAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit})
 
 	assetTransfer := chaincode.SmartContract{}
-	err := assetTransfer.CreateAsset(transactionContext, "", "", 0, "", 0)
+	err = assetTransfer.CreateAsset(transactionContext, "", 0, 1, 1, 0, 1, 0, 0, 0, 1, 1, 0)
+	commit()
 	require.NoError(t, err)
 
+	chaincodeStub.GetStateCalls(nil)
 	chaincodeStub.GetStateReturns([]byte{}, nil)
-	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 0, 1, 1, 0, 1, 0, 0, 0, 1, 1, 0)
+	commit()
 	require.EqualError(t, err, "the asset asset1 already exists")
 
 	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 0, 1, 1, 0, 1, 0, 0, 0, 1, 1, 0)
+	commit()
 	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
 }
 
@@ -64,6 +221,7 @@ func TestReadAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
 
 	expectedAsset := &chaincode.Asset{ID: "asset1"}
 	bytes, err := json.Marshal(expectedAsset)
@@ -89,22 +247,25 @@ func TestUpdateAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
 
-	expectedAsset := &chaincode.Asset{ID: "asset1"}
+	setCaller(t, chaincodeStub, "org1admin")
+
+	expectedAsset := &chaincode.Asset{ID: "asset1", Owner: "org1admin"}
 	bytes, err := json.Marshal(expectedAsset)
 	require.NoError(t, err)
 
 	chaincodeStub.GetStateReturns(bytes, nil)
 	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.UpdateAsset(transactionContext, "", "", 0, "", 0)
+	err = assetTransfer.UpdateAsset(transactionContext, "", 0, 1, 1, 0, 1)
 	require.NoError(t, err)
 
 	chaincodeStub.GetStateReturns(nil, nil)
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", 0, 1, 1, 0, 1)
 	require.EqualError(t, err, "the asset asset1 does not exist")
 
 	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", 0, 1, 1, 0, 1)
 	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
 }
 
@@ -112,8 +273,11 @@ func TestDeleteAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
 
-	asset := &chaincode.Asset{ID: "asset1"}
+	setCaller(t, chaincodeStub, "org1admin")
+
+	asset := &chaincode.Asset{ID: "asset1", Owner: "org1admin"}
 	bytes, err := json.Marshal(asset)
 	require.NoError(t, err)
 
@@ -136,12 +300,23 @@ func TestTransferAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
 
-	asset := &chaincode.Asset{ID: "asset1"}
+	setCaller(t, chaincodeStub, "org1admin")
+
+	asset := &chaincode.Asset{ID: "asset1", Owner: "org1admin"}
 	bytes, err := json.Marshal(asset)
 	require.NoError(t, err)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
+	// the asset is returned for every key except the policy records, which are composite keys
+	chaincodeStub.CreateCompositeKeyCalls(shim.CreateCompositeKey)
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		if strings.HasPrefix(key, "\x00") {
+			return nil, nil
+		}
+		return bytes, nil
+	})
+	chaincodeStub.GetStateByPartialCompositeKeyReturns(&mocks.StateQueryIterator{}, nil)
 	assetTransfer := chaincode.SmartContract{}
 	_, err = assetTransfer.TransferAsset(transactionContext, "", "")
 	require.NoError(t, err)
@@ -164,6 +339,7 @@ func TestGetAllAssets(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
 
 	chaincodeStub.GetStateByRangeReturns(iterator, nil)
 	assetTransfer := &chaincode.SmartContract{}
@@ -0,0 +1,129 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const statementObjectType = "statement"
+
+// CreditStatement is a snapshot of a participant's credit that can be exported and verified off-chain.
+// ID is the id of the issuing transaction, which locates the block the statement was committed in.
+// Hash is the hex encoded SHA-256 digest of the statement JSON with an empty Hash.
+type CreditStatement struct {
+	ID          string    `json:"ID"`
+	Participant string    `json:"Participant"`
+	Grade       string    `json:"Grade"`
+	FinalScore  float32   `json:"FinalScore"`
+	Transaction float32   `json:"Transaction"`
+	Issuer      string    `json:"Issuer"`
+	IssuedAt    time.Time `json:"IssuedAt"`
+	Expiry      time.Time `json:"Expiry"`
+	Hash        string    `json:"Hash"`
+}
+
+// IssueCreditStatement records a statement of a participant's current credit that expires after validDays.
+// Only the participant itself and the consortium admins can issue it.
+func (s *SmartContract) IssueCreditStatement(ctx contractapi.TransactionContextInterface, id string, validDays int) (*CreditStatement, error) {
+	if validDays <= 0 {
+		return nil, fmt.Errorf("the validity of a statement must be at least one day")
+	}
+
+	full, err := s.canViewCredit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !full {
+		return nil, fmt.Errorf("only %s and the consortium admins can issue its credit statement", id)
+	}
+
+	credit, err := s.readCredit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := CreditStatement{
+		ID:          ctx.GetStub().GetTxID(),
+		Participant: id,
+		Grade:       credit.Grade,
+		FinalScore:  credit.FinalScore,
+		Transaction: credit.Transaction,
+		Issuer:      caller,
+		IssuedAt:    timestamp,
+		Expiry:      timestamp.AddDate(0, 0, validDays),
+	}
+	statement.Hash, err = statementHash(&statement)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(statementObjectType, []string{statement.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	statementJSON, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.GetStub().PutState(key, statementJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state. %v", err)
+	}
+
+	return &statement, nil
+}
+
+// ReadCreditStatement returns the credit statement issued by the given transaction.
+func (s *SmartContract) ReadCreditStatement(ctx contractapi.TransactionContextInterface, id string) (*CreditStatement, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(statementObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	statementJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if statementJSON == nil {
+		return nil, fmt.Errorf("the credit statement %s does not exist", id)
+	}
+
+	var statement CreditStatement
+	err = json.Unmarshal(statementJSON, &statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statement, nil
+}
+
+// statementHash returns the hex encoded SHA-256 digest of the statement JSON with an empty Hash.
+func statementHash(statement *CreditStatement) (string, error) {
+	unhashed := *statement
+	unhashed.Hash = ""
+
+	statementJSON, err := json.Marshal(unhashed)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(statementJSON)
+	return hex.EncodeToString(digest[:]), nil
+}
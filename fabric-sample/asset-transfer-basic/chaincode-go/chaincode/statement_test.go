@@ -0,0 +1,54 @@
+package chaincode_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueCreditStatement(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCallerWithMSP(t, chaincodeStub, "org2admin", "Org2MSP")
+	chaincodeStub.GetTxIDReturns("tx1")
+
+	credit, err := json.Marshal(&chaincode.Credit{ID: "org2admin", Transaction: 4, Score: 3.8, FinalScore: 0.95})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org2admin": credit, "org5admin": credit})
+
+	assetTransfer := chaincode.SmartContract{}
+	_, err = assetTransfer.IssueCreditStatement(transactionContext, "org5admin", 30)
+	commit()
+	require.EqualError(t, err, "only org5admin and the consortium admins can issue its credit statement")
+	_, err = assetTransfer.IssueCreditStatement(transactionContext, "org2admin", 0)
+	commit()
+	require.EqualError(t, err, "the validity of a statement must be at least one day")
+
+	statement, err := assetTransfer.IssueCreditStatement(transactionContext, "org2admin", 30)
+	commit()
+	require.NoError(t, err)
+	require.Equal(t, "A", statement.Grade)
+	require.Equal(t, "org2admin", statement.Issuer)
+	require.True(t, statement.Expiry.Equal(time.Date(2023, 4, 14, 0, 0, 0, 0, time.UTC)))
+
+	recorded, err := assetTransfer.ReadCreditStatement(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Equal(t, statement.Hash, recorded.Hash)
+
+	recorded.Hash = ""
+	unhashed, err := json.Marshal(recorded)
+	require.NoError(t, err)
+	digest := sha256.Sum256(unhashed)
+	require.Equal(t, hex.EncodeToString(digest[:]), statement.Hash)
+
+	_, err = assetTransfer.ReadCreditStatement(transactionContext, "tx2")
+	require.EqualError(t, err, "the credit statement tx2 does not exist")
+}
@@ -0,0 +1,181 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// CheckpointStat holds the pass and fail counts of one checkpoint within a group
+type CheckpointStat struct {
+	Group      string  `json:"Group"`
+	Checkpoint string  `json:"Checkpoint"`
+	Passed     int     `json:"Passed"`
+	Failed     int     `json:"Failed"`
+	PassRate   float32 `json:"PassRate"`
+	FailRate   float32 `json:"FailRate"`
+}
+
+// CheckpointStatsResult is a page of checkpoint statistics and the bookmark of the next page
+type CheckpointStatsResult struct {
+	Stats               []*CheckpointStat `json:"stats"`
+	FetchedRecordsCount int32             `json:"fetchedRecordsCount"`
+	Bookmark            string            `json:"bookmark"`
+}
+
+// GetCheckpointStats returns pass and fail counts per checkpoint computed from the contribution records
+// between from and to (RFC 3339, empty for an open bound). groupBy is one of "participant", "month" or
+// "category". Callers other than the consortium admins only get their own statistics. A page reads up to
+// pageSize contribution records, starting at the bookmark, and the statistics cover the records of that
+// page within the bounds only: clients sum the counts over all pages, passing the returned bookmark
+// until it is empty. Paginated queries can only be evaluated, not submitted.
+func (s *SmartContract) GetCheckpointStats(ctx contractapi.TransactionContextInterface, from string, to string, groupBy string, pageSize int, bookmark string) (*CheckpointStatsResult, error) {
+	if groupBy != "participant" && groupBy != "month" && groupBy != "category" {
+		return nil, fmt.Errorf("unsupported groupBy %q, expected participant, month or category", groupBy)
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.isConsortiumAdmin(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	fromTime, err := parseBound(from)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := parseBound(to)
+	if err != nil {
+		return nil, err
+	}
+
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("the page size must be positive")
+	}
+
+	// the contribution keys start with the participant, so participants only read their own records;
+	// the time bounds are not part of the keys and are applied to the records of the page
+	var attributes []string
+	if !admin {
+		attributes = []string{caller}
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(contributionObjectType, attributes, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	result := &CheckpointStatsResult{
+		Stats:               []*CheckpointStat{},
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}
+	stats := make(map[[2]string]*CheckpointStat)
+	categories := make(map[string]string)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var contribution Contribution
+		err = json.Unmarshal(queryResponse.Value, &contribution)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fromTime.IsZero() && contribution.TimeStamp.Before(fromTime) {
+			continue
+		}
+		if !toTime.IsZero() && contribution.TimeStamp.After(toTime) {
+			continue
+		}
+
+		var group string
+		switch groupBy {
+		case "participant":
+			group = contribution.Participant
+		case "month":
+			group = contribution.TimeStamp.UTC().Format("2006-01")
+		case "category":
+			category, ok := categories[contribution.AssetID]
+			if !ok {
+				category, err = s.assetCategory(ctx, contribution.AssetID)
+				if err != nil {
+					return nil, err
+				}
+				categories[contribution.AssetID] = category
+			}
+			group = category
+		}
+
+		key := [2]string{group, contribution.Checkpoint}
+		stat, ok := stats[key]
+		if !ok {
+			stat = &CheckpointStat{Group: group, Checkpoint: contribution.Checkpoint}
+			stats[key] = stat
+		}
+		if contribution.Passed {
+			stat.Passed++
+		} else {
+			stat.Failed++
+		}
+	}
+
+	for _, stat := range stats {
+		total := float32(stat.Passed + stat.Failed)
+		stat.PassRate = float32(stat.Passed) / total
+		stat.FailRate = float32(stat.Failed) / total
+		result.Stats = append(result.Stats, stat)
+	}
+	sort.Slice(result.Stats, func(i, j int) bool {
+		if result.Stats[i].Group != result.Stats[j].Group {
+			return result.Stats[i].Group < result.Stats[j].Group
+		}
+		return result.Stats[i].Checkpoint < result.Stats[j].Checkpoint
+	})
+
+	return result, nil
+}
+
+// assetCategory returns the category of an asset, or "uncategorized" when the asset has none or was deleted.
+func (s *SmartContract) assetCategory(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return "uncategorized", nil
+	}
+
+	var asset Asset
+	err = json.Unmarshal(assetJSON, &asset)
+	if err != nil {
+		return "", err
+	}
+	if asset.Category == "" {
+		return "uncategorized", nil
+	}
+
+	return asset.Category, nil
+}
+
+// parseBound parses an RFC 3339 time range bound, returning the zero time for an empty bound.
+func parseBound(bound string) (time.Time, error) {
+	if bound == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, bound)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339: %v", bound, err)
+	}
+
+	return t, nil
+}
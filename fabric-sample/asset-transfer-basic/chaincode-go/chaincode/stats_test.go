@@ -0,0 +1,91 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCheckpointStats(t *testing.T) {
+	contributions := []*chaincode.Contribution{
+		{Participant: "org5admin", AssetID: "asset1", Checkpoint: "B01", Passed: false, TxID: "tx1", TimeStamp: time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)},
+		{Participant: "org5admin", AssetID: "asset2", Checkpoint: "B01", Passed: true, TxID: "tx2", TimeStamp: time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{Participant: "org5admin", AssetID: "asset3", Checkpoint: "B01", Passed: false, TxID: "tx3", TimeStamp: time.Date(2023, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{Participant: "org2admin", AssetID: "asset1", Checkpoint: "A01", Passed: true, TxID: "tx1", TimeStamp: time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	state := map[string][]byte{}
+	for _, contribution := range contributions {
+		key, err := shim.CreateCompositeKey("contribution", []string{contribution.Participant, contribution.AssetID, contribution.Checkpoint, contribution.TxID})
+		require.NoError(t, err)
+		bytes, err := json.Marshal(contribution)
+		require.NoError(t, err)
+		state[key] = bytes
+	}
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+	setState(chaincodeStub, state)
+
+	assetTransfer := chaincode.SmartContract{}
+	result, err := assetTransfer.GetCheckpointStats(transactionContext, "2023-03-01T00:00:00Z", "", "participant", 10, "")
+	require.NoError(t, err)
+	require.Equal(t, "", result.Bookmark)
+	require.Equal(t, int32(4), result.FetchedRecordsCount)
+	require.Equal(t, []*chaincode.CheckpointStat{
+		{Group: "org2admin", Checkpoint: "A01", Passed: 1, Failed: 0, PassRate: 1, FailRate: 0},
+		{Group: "org5admin", Checkpoint: "B01", Passed: 1, Failed: 1, PassRate: 0.5, FailRate: 0.5},
+	}, result.Stats)
+
+	var pages, records, counted int
+	bookmark := ""
+	for {
+		result, err = assetTransfer.GetCheckpointStats(transactionContext, "2023-03-01T00:00:00Z", "", "month", 1, bookmark)
+		require.NoError(t, err)
+		pages++
+		records += int(result.FetchedRecordsCount)
+		for _, stat := range result.Stats {
+			counted += stat.Passed + stat.Failed
+		}
+		bookmark = result.Bookmark
+		if bookmark == "" {
+			break
+		}
+	}
+	require.Equal(t, 4, pages, "each page starts at the bookmark")
+	require.Equal(t, 4, records)
+	require.Equal(t, 3, counted, "records outside the bounds are not counted")
+
+	setCaller(t, chaincodeStub, "org2admin")
+	result, err = assetTransfer.GetCheckpointStats(transactionContext, "2023-03-01T00:00:00Z", "", "participant", 10, "")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.CheckpointStat{
+		{Group: "org2admin", Checkpoint: "A01", Passed: 1, Failed: 0, PassRate: 1, FailRate: 0},
+	}, result.Stats, "participants only get their own statistics")
+
+	for _, groupBy := range []string{"month", "category"} {
+		result, err = assetTransfer.GetCheckpointStats(transactionContext, "", "", groupBy, 10, "")
+		require.NoError(t, err)
+		require.Equal(t, int32(1), result.FetchedRecordsCount)
+		require.Len(t, result.Stats, 1)
+		require.Equal(t, "A01", result.Stats[0].Checkpoint, "participants only get their own statistics grouped by %s", groupBy)
+	}
+
+	_, err = assetTransfer.GetCheckpointStats(transactionContext, "", "", "owner", 10, "")
+	require.EqualError(t, err, `unsupported groupBy "owner", expected participant, month or category`)
+	_, err = assetTransfer.GetCheckpointStats(transactionContext, "", "", "month", 0, "")
+	require.EqualError(t, err, "the page size must be positive")
+
+	chaincodeStub.GetStateByPartialCompositeKeyWithPaginationReturns(nil, nil, fmt.Errorf("failed retrieving contributions"))
+	_, err = assetTransfer.GetCheckpointStats(transactionContext, "", "", "month", 10, "")
+	require.EqualError(t, err, "failed retrieving contributions")
+}
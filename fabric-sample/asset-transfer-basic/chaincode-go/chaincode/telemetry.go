@@ -0,0 +1,465 @@
+package chaincode
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	deviceObjectType             = "device"
+	telemetryThresholdObjectType = "telemetryThreshold"
+	telemetryEvidenceObjectType  = "telemetryEvidence"
+)
+
+// Device is a registered sensor identity. Sequence is the sequence number of the last accepted batch
+// and Assets the assets the device may report on.
+type Device struct {
+	ID        string   `json:"ID"`
+	PublicKey string   `json:"PublicKey"`
+	Sequence  int      `json:"Sequence"`
+	Assets    []string `json:"Assets,omitempty" metadata:",optional"`
+}
+
+// TelemetryThreshold is the accepted range of a metric for assets of a category, and the checkpoint
+// that fails when a reading falls outside it
+type TelemetryThreshold struct {
+	Category   string  `json:"Category"`
+	Metric     string  `json:"Metric"`
+	Min        float64 `json:"Min"`
+	Max        float64 `json:"Max"`
+	Checkpoint string  `json:"Checkpoint"`
+}
+
+// Reading is a single sensor measurement
+type Reading struct {
+	Metric    string    `json:"Metric"`
+	Value     float64   `json:"Value"`
+	TimeStamp time.Time `json:"TimeStamp"`
+}
+
+// TelemetryBatch is the payload a device signs. Sequence must increase with every batch of the device.
+type TelemetryBatch struct {
+	AssetID  string    `json:"AssetID"`
+	Sequence int       `json:"Sequence"`
+	Readings []Reading `json:"Readings"`
+}
+
+// TelemetryEvidence keeps the readings that failed a checkpoint of an asset
+type TelemetryEvidence struct {
+	AssetID    string    `json:"AssetID"`
+	DeviceID   string    `json:"DeviceID"`
+	Checkpoint string    `json:"Checkpoint"`
+	Readings   []Reading `json:"Readings"`
+	TxID       string    `json:"TxID"`
+	TimeStamp  time.Time `json:"TimeStamp"`
+}
+
+// RegisterDevice registers a sensor with its PEM encoded ECDSA public key or certificate.
+func (s *SmartContract) RegisterDevice(ctx contractapi.TransactionContextInterface, deviceID string, publicKeyPEM string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putDevice(ctx, deviceID, publicKeyPEM)
+}
+
+// BindDevice allows or disallows a registered device to report on an asset. Only the owner of the
+// asset can bind devices to it.
+func (s *SmartContract) BindDevice(ctx contractapi.TransactionContextInterface, deviceID string, assetID string, bound bool) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+	if asset.Owner != caller {
+		return fmt.Errorf("only the owner of the asset can bind devices to it")
+	}
+
+	device, err := s.ReadDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+
+	assets := []string{}
+	for _, id := range device.Assets {
+		if id != assetID {
+			assets = append(assets, id)
+		}
+	}
+	if bound {
+		assets = append(assets, assetID)
+	}
+	device.Assets = assets
+
+	return s.putDeviceRecord(ctx, device)
+}
+
+// SetTelemetryThreshold sets the accepted range of a metric for assets of a category and the
+// checkpoint that fails when a reading falls outside it.
+func (s *SmartContract) SetTelemetryThreshold(ctx contractapi.TransactionContextInterface, category string, metric string, min float64, max float64, checkpointName string) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putTelemetryThreshold(ctx, category, metric, min, max, checkpointName)
+}
+
+// SubmitTelemetry accepts a batch of readings signed by a registered device bound to the asset. The
+// payload is the base64 encoded JSON TelemetryBatch and the signature its base64 encoded ASN.1 ECDSA
+// signature over the SHA-256 digest of the payload. Readings outside the thresholds of the asset's
+// category fail the related checkpoint, which is then scored against the submitter, and are kept as
+// evidence. Dual inspected checkpoints are left to their inspectors.
+func (s *SmartContract) SubmitTelemetry(ctx contractapi.TransactionContextInterface, deviceID string, payload string, signature string) error {
+	device, err := s.ReadDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+
+	payloadBytes, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("failed to decode payload: %v", err)
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	publicKey, err := parsePublicKey(device.PublicKey)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(payloadBytes)
+	if !ecdsa.VerifyASN1(publicKey, digest[:], signatureBytes) {
+		return fmt.Errorf("the telemetry signature of device %s is invalid", deviceID)
+	}
+
+	var batch TelemetryBatch
+	err = json.Unmarshal(payloadBytes, &batch)
+	if err != nil {
+		return fmt.Errorf("failed to parse payload: %v", err)
+	}
+
+	if !device.reportsOn(batch.AssetID) {
+		return fmt.Errorf("the device %s is not bound to asset %s", deviceID, batch.AssetID)
+	}
+	if batch.Sequence <= device.Sequence {
+		return fmt.Errorf("the batch %d of device %s has already been submitted", batch.Sequence, deviceID)
+	}
+	device.Sequence = batch.Sequence
+	err = s.putDeviceRecord(ctx, device)
+	if err != nil {
+		return err
+	}
+
+	asset, err := s.ReadAsset(ctx, batch.AssetID)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	violations := make(map[string][]Reading)
+	for _, reading := range batch.Readings {
+		threshold, err := s.readTelemetryThreshold(ctx, asset.Category, reading.Metric)
+		if err != nil {
+			return err
+		}
+		if threshold == nil || (reading.Value >= threshold.Min && reading.Value <= threshold.Max) {
+			continue
+		}
+
+		if _, ok := violations[threshold.Checkpoint]; !ok {
+			failed = append(failed, threshold.Checkpoint)
+		}
+		violations[threshold.Checkpoint] = append(violations[threshold.Checkpoint], reading)
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	var rescored []string
+	for _, name := range failed {
+		evidence := TelemetryEvidence{
+			AssetID:    asset.ID,
+			DeviceID:   deviceID,
+			Checkpoint: name,
+			Readings:   violations[name],
+			TxID:       ctx.GetStub().GetTxID(),
+			TimeStamp:  timestamp,
+		}
+		err = s.putTelemetryEvidence(ctx, &evidence)
+		if err != nil {
+			return err
+		}
+
+		// a dual inspected checkpoint is only decided by its inspectors, the evidence is kept for them
+		dual, err := s.readDualInspection(ctx, name)
+		if err != nil {
+			return err
+		}
+		if dual != nil {
+			continue
+		}
+
+		// a checkpoint that already failed, or does not apply, is not penalized again for the same asset
+		if checkpointResult(asset, name) != 0 && !notApplicable(asset, name) {
+			setCheckpointResult(asset, name, 0)
+			rescored = append(rescored, name)
+		}
+	}
+
+	if len(rescored) == 0 {
+		return nil
+	}
+
+	err = s.scoreCheckpoints(ctx, caller, asset, rescored)
+	if err != nil {
+		return err
+	}
+
+	asset.Function = "SubmitTelemetry"
+	asset.Sender = caller
+	asset.TimeStamp = timestamp
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(asset.ID, assetJSON)
+}
+
+// ReadDevice returns the registered device with the given id.
+func (s *SmartContract) ReadDevice(ctx contractapi.TransactionContextInterface, deviceID string) (*Device, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(deviceObjectType, []string{deviceID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	deviceJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if deviceJSON == nil {
+		return nil, fmt.Errorf("the device %s is not registered", deviceID)
+	}
+
+	var device Device
+	err = json.Unmarshal(deviceJSON, &device)
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// reportsOn reports whether the device is bound to an asset.
+func (device *Device) reportsOn(assetID string) bool {
+	for _, id := range device.Assets {
+		if id == assetID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTelemetryEvidence returns the readings that failed checkpoints of an asset.
+func (s *SmartContract) GetTelemetryEvidence(ctx contractapi.TransactionContextInterface, assetID string) ([]*TelemetryEvidence, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(telemetryEvidenceObjectType, []string{assetID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*TelemetryEvidence
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var evidence TelemetryEvidence
+		err = json.Unmarshal(queryResponse.Value, &evidence)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &evidence)
+	}
+
+	return result, nil
+}
+
+// validateDevice checks that a new device can be registered with the given public key.
+func (s *SmartContract) validateDevice(ctx contractapi.TransactionContextInterface, deviceID string, publicKeyPEM string) error {
+	_, err := parsePublicKey(publicKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ReadDevice(ctx, deviceID)
+	if err == nil {
+		return fmt.Errorf("the device %s is already registered", deviceID)
+	}
+
+	return nil
+}
+
+// putDevice registers a new device.
+func (s *SmartContract) putDevice(ctx contractapi.TransactionContextInterface, deviceID string, publicKeyPEM string) error {
+	err := s.validateDevice(ctx, deviceID, publicKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	return s.putDeviceRecord(ctx, &Device{ID: deviceID, PublicKey: publicKeyPEM})
+}
+
+func (s *SmartContract) putDeviceRecord(ctx contractapi.TransactionContextInterface, device *Device) error {
+	key, err := ctx.GetStub().CreateCompositeKey(deviceObjectType, []string{device.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	deviceJSON, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, deviceJSON)
+}
+
+// validateTelemetryThreshold checks the threshold of a metric before it is stored.
+func validateTelemetryThreshold(metric string, min float64, max float64, checkpointName string) error {
+	if metric != "temperature" && metric != "humidity" && metric != "shock" {
+		return fmt.Errorf("unsupported metric %q, expected temperature, humidity or shock", metric)
+	}
+	if min > max {
+		return fmt.Errorf("the minimum must not be greater than the maximum")
+	}
+
+	_, err := findCheckpoint(checkpointName)
+	return err
+}
+
+// putTelemetryThreshold stores the threshold of a metric for a category.
+func (s *SmartContract) putTelemetryThreshold(ctx contractapi.TransactionContextInterface, category string, metric string, min float64, max float64, checkpointName string) error {
+	err := validateTelemetryThreshold(metric, min, max, checkpointName)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(telemetryThresholdObjectType, []string{category, metric})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	threshold := TelemetryThreshold{
+		Category:   category,
+		Metric:     metric,
+		Min:        min,
+		Max:        max,
+		Checkpoint: checkpointName,
+	}
+	thresholdJSON, err := json.Marshal(threshold)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, thresholdJSON)
+}
+
+// readTelemetryThreshold returns the threshold of a metric for a category, or nil if it has none.
+func (s *SmartContract) readTelemetryThreshold(ctx contractapi.TransactionContextInterface, category string, metric string) (*TelemetryThreshold, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(telemetryThresholdObjectType, []string{category, metric})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	thresholdJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if thresholdJSON == nil {
+		return nil, nil
+	}
+
+	var threshold TelemetryThreshold
+	err = json.Unmarshal(thresholdJSON, &threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &threshold, nil
+}
+
+func (s *SmartContract) putTelemetryEvidence(ctx contractapi.TransactionContextInterface, evidence *TelemetryEvidence) error {
+	key, err := ctx.GetStub().CreateCompositeKey(telemetryEvidenceObjectType, []string{evidence.AssetID, evidence.TxID, evidence.Checkpoint})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	evidenceJSON, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, evidenceJSON)
+}
+
+// parsePublicKey parses a PEM encoded ECDSA public key or certificate.
+func parsePublicKey(publicKeyPEM string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode the public key PEM")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	case "PUBLIC KEY":
+		var err error
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the device key must be an ECDSA key")
+	}
+
+	return publicKey, nil
+}
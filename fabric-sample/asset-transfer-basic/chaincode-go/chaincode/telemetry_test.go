@@ -0,0 +1,168 @@
+package chaincode_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitTelemetry(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	asset, err := json.Marshal(&chaincode.Asset{ID: "asset1", Owner: "org1admin", Category: "cold-chain", A01: 1, A02: 1})
+	require.NoError(t, err)
+	state := map[string][]byte{"asset1": asset, "org2admin": credit}
+	commit := setState(chaincodeStub, state)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.RegisterDevice(transactionContext, "sensor1", publicKeyPEM)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetTelemetryThreshold(transactionContext, "cold-chain", "temperature", 2, 8, "A01")
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetTelemetryThreshold(transactionContext, "cold-chain", "pressure", 0, 1, "A01")
+	commit()
+	require.EqualError(t, err, `unsupported metric "pressure", expected temperature, humidity or shock`)
+
+	sign := func(batch chaincode.TelemetryBatch) (string, string) {
+		payload, err := json.Marshal(batch)
+		require.NoError(t, err)
+		digest := sha256.Sum256(payload)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(payload), base64.StdEncoding.EncodeToString(signature)
+	}
+
+	readAt := time.Date(2023, 3, 14, 8, 0, 0, 0, time.UTC)
+	payload, signature := sign(chaincode.TelemetryBatch{AssetID: "asset1", Sequence: 1, Readings: []chaincode.Reading{
+		{Metric: "temperature", Value: 5, TimeStamp: readAt},
+		{Metric: "humidity", Value: 99, TimeStamp: readAt},
+	}})
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.EqualError(t, err, "the device sensor1 is not bound to asset asset1")
+
+	setCaller(t, chaincodeStub, "org2admin")
+	err = assetTransfer.BindDevice(transactionContext, "sensor1", "asset1", true)
+	commit()
+	require.EqualError(t, err, "only the owner of the asset can bind devices to it")
+	setCaller(t, chaincodeStub, "org1admin")
+	err = assetTransfer.BindDevice(transactionContext, "sensor2", "asset1", true)
+	commit()
+	require.EqualError(t, err, "the device sensor2 is not registered")
+	err = assetTransfer.BindDevice(transactionContext, "sensor1", "asset1", true)
+	commit()
+	require.NoError(t, err)
+
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.NoError(t, err)
+
+	evidence, err := assetTransfer.GetTelemetryEvidence(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Empty(t, evidence, "readings within range or without threshold are not evidence")
+
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.EqualError(t, err, "the batch 1 of device sensor1 has already been submitted")
+
+	payload, _ = sign(chaincode.TelemetryBatch{AssetID: "asset1", Sequence: 2, Readings: []chaincode.Reading{
+		{Metric: "temperature", Value: 12.5, TimeStamp: readAt.Add(time.Hour)},
+	}})
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.EqualError(t, err, "the telemetry signature of device sensor1 is invalid")
+
+	payload, signature = sign(chaincode.TelemetryBatch{AssetID: "asset1", Sequence: 2, Readings: []chaincode.Reading{
+		{Metric: "temperature", Value: 12.5, TimeStamp: readAt.Add(time.Hour)},
+	}})
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.NoError(t, err)
+
+	updated, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, 0, updated.A01)
+	require.Equal(t, "SubmitTelemetry", updated.Function)
+
+	evidence, err = assetTransfer.GetTelemetryEvidence(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Len(t, evidence, 1)
+	require.Equal(t, "A01", evidence[0].Checkpoint)
+	require.Equal(t, 12.5, evidence[0].Readings[0].Value)
+
+	require.Equal(t, "org1admin", updated.Sender, "the submitter rates the checkpoint, not the device")
+
+	org2, err := assetTransfer.ReadCredit(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(0.75), org2.Score)
+
+	contributions, err := assetTransfer.GetContributions(transactionContext, "org2admin")
+	require.NoError(t, err)
+	require.Len(t, contributions, 1)
+	require.Equal(t, "org1admin", contributions[0].Rater)
+
+	err = assetTransfer.SetDualInspection(transactionContext, "A02", "org3admin")
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetTelemetryThreshold(transactionContext, "cold-chain", "humidity", 0, 80, "A02")
+	commit()
+	require.NoError(t, err)
+	payload, signature = sign(chaincode.TelemetryBatch{AssetID: "asset1", Sequence: 3, Readings: []chaincode.Reading{
+		{Metric: "humidity", Value: 95, TimeStamp: readAt.Add(2 * time.Hour)},
+	}})
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.NoError(t, err)
+
+	updated, err = assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, 1, updated.A02, "dual inspected checkpoints are left to their inspectors")
+	evidence, err = assetTransfer.GetTelemetryEvidence(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Len(t, evidence, 2)
+
+	payload, signature = sign(chaincode.TelemetryBatch{AssetID: "asset2", Sequence: 4, Readings: []chaincode.Reading{
+		{Metric: "temperature", Value: 30, TimeStamp: readAt.Add(3 * time.Hour)},
+	}})
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.EqualError(t, err, "the device sensor1 is not bound to asset asset2", "a device only reports on the assets it is bound to")
+
+	err = assetTransfer.BindDevice(transactionContext, "sensor1", "asset1", false)
+	commit()
+	require.NoError(t, err)
+	device, err := assetTransfer.ReadDevice(transactionContext, "sensor1")
+	require.NoError(t, err)
+	require.Empty(t, device.Assets)
+	payload, signature = sign(chaincode.TelemetryBatch{AssetID: "asset1", Sequence: 4, Readings: []chaincode.Reading{
+		{Metric: "temperature", Value: 5, TimeStamp: readAt.Add(3 * time.Hour)},
+	}})
+	err = assetTransfer.SubmitTelemetry(transactionContext, "sensor1", payload, signature)
+	commit()
+	require.EqualError(t, err, "the device sensor1 is not bound to asset asset1")
+}
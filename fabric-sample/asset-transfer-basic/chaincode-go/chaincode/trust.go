@@ -0,0 +1,207 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	trustObjectType          = "trust"
+	trustThresholdObjectType = "trustThreshold"
+)
+
+// Trust is the score a rater has given a ratee through the checkpoints the rater evaluated about the ratee
+type Trust struct {
+	Rater       string  `json:"Rater"`
+	Ratee       string  `json:"Ratee"`
+	Transaction float32 `json:"Transaction"`
+	Score       float32 `json:"Score"`
+	FinalScore  float32 `json:"FinalScore"`
+}
+
+// TrustThreshold is the minimum trust a rater requires of a ratee before transferring assets to it
+type TrustThreshold struct {
+	Rater    string  `json:"Rater"`
+	Ratee    string  `json:"Ratee"`
+	MinScore float32 `json:"MinScore"`
+}
+
+// ReadTrust returns the trust the rater has in the ratee. Only the rater, the ratee and the
+// consortium admins may read it.
+func (s *SmartContract) ReadTrust(ctx contractapi.TransactionContextInterface, rater string, ratee string) (*Trust, error) {
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if caller != rater && caller != ratee {
+		admin, err := s.isConsortiumAdmin(ctx, caller)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, fmt.Errorf("only %s, %s and the consortium admins can read their trust", rater, ratee)
+		}
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(trustObjectType, []string{rater, ratee})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	trustJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if trustJSON == nil {
+		return nil, fmt.Errorf("the trust of %s in %s does not exist", rater, ratee)
+	}
+
+	var trust Trust
+	err = json.Unmarshal(trustJSON, &trust)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trust, nil
+}
+
+// GetTrustMatrix returns the trust of every rater in every ratee it has evaluated. Participants only
+// see the trust they give or receive, the consortium admins the whole matrix.
+func (s *SmartContract) GetTrustMatrix(ctx contractapi.TransactionContextInterface) ([]*Trust, error) {
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.isConsortiumAdmin(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(trustObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*Trust
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var trust Trust
+		err = json.Unmarshal(queryResponse.Value, &trust)
+		if err != nil {
+			return nil, err
+		}
+
+		if !admin && trust.Rater != caller && trust.Ratee != caller {
+			continue
+		}
+		result = append(result, &trust)
+	}
+
+	return result, nil
+}
+
+// SetTrustThreshold sets the minimum trust the caller requires of the ratee for TransferAsset to succeed.
+// A threshold of 0 removes the requirement.
+func (s *SmartContract) SetTrustThreshold(ctx contractapi.TransactionContextInterface, ratee string, minScore float32) error {
+	if minScore < 0 || minScore > 1 {
+		return fmt.Errorf("the threshold must be between 0 and 1")
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(trustThresholdObjectType, []string{caller, ratee})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if minScore == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+
+	threshold := TrustThreshold{
+		Rater:    caller,
+		Ratee:    ratee,
+		MinScore: minScore,
+	}
+	thresholdJSON, err := json.Marshal(threshold)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, thresholdJSON)
+}
+
+// updateTrust adds the results of the checkpoints the rater evaluated about the ratee in a transaction to
+// their trust score: checks checkpoints, of which passes passed.
+func (s *SmartContract) updateTrust(ctx contractapi.TransactionContextInterface, rater string, ratee string, passes int, checks int) error {
+	key, err := ctx.GetStub().CreateCompositeKey(trustObjectType, []string{rater, ratee})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	trustJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	trust := Trust{Rater: rater, Ratee: ratee}
+	if trustJSON != nil {
+		err = json.Unmarshal(trustJSON, &trust)
+		if err != nil {
+			return err
+		}
+	}
+
+	trust.Score += float32(passes)
+	trust.Transaction += float32(checks)
+	trust.FinalScore = trust.Score / trust.Transaction
+
+	trustJSON, err = json.Marshal(trust)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, trustJSON)
+}
+
+// checkTrustThreshold returns an error when the rater has set a threshold for the ratee that their trust does not meet.
+func (s *SmartContract) checkTrustThreshold(ctx contractapi.TransactionContextInterface, rater string, ratee string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(trustThresholdObjectType, []string{rater, ratee})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	thresholdJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if thresholdJSON == nil {
+		return nil
+	}
+
+	var threshold TrustThreshold
+	err = json.Unmarshal(thresholdJSON, &threshold)
+	if err != nil {
+		return err
+	}
+
+	trust, err := s.ReadTrust(ctx, rater, ratee)
+	if err != nil {
+		return fmt.Errorf("%s requires a trust of %v in %s, but none has been recorded", rater, threshold.MinScore, ratee)
+	}
+	if trust.FinalScore < threshold.MinScore {
+		return fmt.Errorf("%s requires a trust of %v in %s, but it is %v", rater, threshold.MinScore, ratee, trust.FinalScore)
+	}
+
+	return nil
+}
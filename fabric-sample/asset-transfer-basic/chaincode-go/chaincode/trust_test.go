@@ -0,0 +1,72 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustThreshold(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	state := map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit}
+	commit := setState(chaincodeStub, state)
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 1, 1, 1, 1, 1, 1, 0, 1, 1, 1, 1)
+	commit()
+	require.NoError(t, err)
+
+	trust, err := assetTransfer.ReadTrust(transactionContext, "org1admin", "org2admin")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Trust{Rater: "org1admin", Ratee: "org2admin", Transaction: 4, Score: 3, FinalScore: 0.75}, trust)
+
+	setCaller(t, chaincodeStub, "org5admin")
+	_, err = assetTransfer.ReadTrust(transactionContext, "org1admin", "org2admin")
+	require.EqualError(t, err, "only org1admin, org2admin and the consortium admins can read their trust")
+	matrix, err := assetTransfer.GetTrustMatrix(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, matrix, 1, "participants only see the trust they give or receive")
+	require.Equal(t, "org5admin", matrix[0].Ratee)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	key, err := shim.CreateCompositeKey("trust", []string{"org1admin", "org1admin"})
+	require.NoError(t, err)
+	require.Nil(t, state[key], "self checks do not count towards trust")
+
+	err = assetTransfer.SetTrustThreshold(transactionContext, "org2admin", 0.8)
+	commit()
+	require.NoError(t, err)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "org2admin")
+	commit()
+	require.EqualError(t, err, "org1admin requires a trust of 0.8 in org2admin, but it is 0.75")
+
+	err = assetTransfer.SetTrustThreshold(transactionContext, "org3admin", 0.5)
+	commit()
+	require.NoError(t, err)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "org3admin")
+	commit()
+	require.EqualError(t, err, "org1admin requires a trust of 0.5 in org3admin, but none has been recorded")
+
+	err = assetTransfer.SetTrustThreshold(transactionContext, "org2admin", 0.7)
+	commit()
+	require.NoError(t, err)
+	oldOwner, err := assetTransfer.TransferAsset(transactionContext, "asset1", "org2admin")
+	commit()
+	require.NoError(t, err)
+	require.Equal(t, "org1admin", oldOwner)
+
+	err = assetTransfer.SetTrustThreshold(transactionContext, "org2admin", 1.5)
+	commit()
+	require.EqualError(t, err, "the threshold must be between 0 and 1")
+}
@@ -0,0 +1,279 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const validityObjectType = "validity"
+
+// Validity is how long the result of a checkpoint stays valid after it was checked. Assets with an
+// expired mandatory checkpoint cannot be transferred until they are re-certified.
+type Validity struct {
+	Checkpoint string `json:"Checkpoint"`
+	Days       int    `json:"Days"`
+	Mandatory  bool   `json:"Mandatory"`
+}
+
+// ExpiringCheckpoint is a checkpoint of an asset whose result expires, or has expired, at ExpiresAt
+type ExpiringCheckpoint struct {
+	AssetID    string    `json:"AssetID"`
+	Checkpoint string    `json:"Checkpoint"`
+	Mandatory  bool      `json:"Mandatory"`
+	ExpiresAt  time.Time `json:"ExpiresAt"`
+	Expired    bool      `json:"Expired"`
+}
+
+// SetCheckpointValidity sets the number of days the result of a checkpoint stays valid.
+// A validity of 0 days removes the limit.
+func (s *SmartContract) SetCheckpointValidity(ctx contractapi.TransactionContextInterface, checkpointName string, days int, mandatory bool) error {
+	err := s.checkPolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.putCheckpointValidity(ctx, checkpointName, days, mandatory)
+}
+
+// GetExpiringAssets returns the checkpoints of all assets that have expired or expire within the given number of days.
+func (s *SmartContract) GetExpiringAssets(ctx contractapi.TransactionContextInterface, withinDays int) ([]*ExpiringCheckpoint, error) {
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	validities, err := s.getValidities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(validities) == 0 {
+		return nil, nil
+	}
+
+	assets, err := s.GetAllAssets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := timestamp.AddDate(0, 0, withinDays)
+	var result []*ExpiringCheckpoint
+	for _, asset := range assets {
+		// the range query also returns the credit records, which have no owner
+		if asset.Owner == "" {
+			continue
+		}
+		for _, validity := range validities {
+			if notApplicable(asset, validity.Checkpoint) {
+				continue
+			}
+			expiresAt := expiry(asset, validity)
+			if expiresAt.After(deadline) {
+				continue
+			}
+			result = append(result, &ExpiringCheckpoint{
+				AssetID:    asset.ID,
+				Checkpoint: validity.Checkpoint,
+				Mandatory:  validity.Mandatory,
+				ExpiresAt:  expiresAt,
+				Expired:    !expiresAt.After(timestamp),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// ReCertify records a fresh result for a checkpoint of an asset, restarting its validity period,
+// and scores it like CreateAsset does.
+func (s *SmartContract) ReCertify(ctx contractapi.TransactionContextInterface, id string, checkpointName string, result int) error {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.GetCallerName(ctx)
+	if err != nil {
+		return err
+	}
+
+	if caller != "org1admin" {
+		return fmt.Errorf("only admin from org1 can use this method")
+	}
+
+	_, err = findCheckpoint(checkpointName)
+	if err != nil {
+		return err
+	}
+
+	err = validateResults([]string{checkpointName}, []int{result})
+	if err != nil {
+		return err
+	}
+
+	dual, err := s.readDualInspection(ctx, checkpointName)
+	if err != nil {
+		return err
+	}
+	if dual != nil {
+		return fmt.Errorf("the checkpoint %s requires dual inspection, use ReportInspection", checkpointName)
+	}
+
+	timestamp, err := s.GetTimeStamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	setCheckpointResult(asset, checkpointName, result)
+	asset.Function = "ReCertify"
+	asset.Sender = caller
+	asset.TimeStamp = timestamp
+
+	err = s.scoreCheckpoints(ctx, caller, asset, []string{checkpointName})
+	if err != nil {
+		return err
+	}
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(id, assetJSON)
+}
+
+// checkValidity returns an error when a mandatory checkpoint of the asset has expired at the given time.
+func (s *SmartContract) checkValidity(ctx contractapi.TransactionContextInterface, asset *Asset, timestamp time.Time) error {
+	validities, err := s.getValidities(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, validity := range validities {
+		if !validity.Mandatory || notApplicable(asset, validity.Checkpoint) {
+			continue
+		}
+		if !expiry(asset, validity).After(timestamp) {
+			return fmt.Errorf("the mandatory checkpoint %s of asset %s has expired, it must be re-certified", validity.Checkpoint, asset.ID)
+		}
+	}
+
+	return nil
+}
+
+// getValidities returns the validity periods of all checkpoints that have one.
+func (s *SmartContract) getValidities(ctx contractapi.TransactionContextInterface) ([]*Validity, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(validityObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var result []*Validity
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var validity Validity
+		err = json.Unmarshal(queryResponse.Value, &validity)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &validity)
+	}
+
+	return result, nil
+}
+
+// checkpointExpired reports whether the result of a checkpoint of the asset has a validity period
+// that has ended at the given time.
+func (s *SmartContract) checkpointExpired(ctx contractapi.TransactionContextInterface, asset *Asset, checkpointName string, timestamp time.Time) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(validityObjectType, []string{checkpointName})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	validityJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if validityJSON == nil {
+		return false, nil
+	}
+
+	var validity Validity
+	err = json.Unmarshal(validityJSON, &validity)
+	if err != nil {
+		return false, err
+	}
+
+	return !expiry(asset, &validity).After(timestamp), nil
+}
+
+// expiry returns when the result of a checkpoint of the asset expires. A checkpoint that has no check
+// time of its own, such as one still awaiting dual inspection, counts from the asset's last recorded
+// result, or from its timestamp if no result was recorded.
+func expiry(asset *Asset, validity *Validity) time.Time {
+	checkedAt, ok := asset.CheckedAt[validity.Checkpoint]
+	if !ok {
+		checkedAt = asset.TimeStamp
+		var last time.Time
+		for _, t := range asset.CheckedAt {
+			if t.After(last) {
+				last = t
+			}
+		}
+		if !last.IsZero() {
+			checkedAt = last
+		}
+	}
+	return checkedAt.AddDate(0, 0, validity.Days)
+}
+
+// validateCheckpointValidity checks the validity period of a checkpoint before it is stored.
+func validateCheckpointValidity(checkpointName string, days int) error {
+	_, err := findCheckpoint(checkpointName)
+	if err != nil {
+		return err
+	}
+
+	if days < 0 {
+		return fmt.Errorf("the validity must not be negative")
+	}
+
+	return nil
+}
+
+// putCheckpointValidity stores the validity period of a checkpoint.
+func (s *SmartContract) putCheckpointValidity(ctx contractapi.TransactionContextInterface, checkpointName string, days int, mandatory bool) error {
+	err := validateCheckpointValidity(checkpointName, days)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(validityObjectType, []string{checkpointName})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	if days == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+
+	validity := Validity{
+		Checkpoint: checkpointName,
+		Days:       days,
+		Mandatory:  mandatory,
+	}
+	validityJSON, err := json.Marshal(validity)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, validityJSON)
+}
@@ -0,0 +1,119 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCheckpointValidity(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetCheckpointValidity(transactionContext, "SaveEquipment", 365, true)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	commit()
+	require.NoError(t, err)
+
+	expiring, err := assetTransfer.GetExpiringAssets(transactionContext, 30)
+	require.NoError(t, err)
+	require.Empty(t, expiring)
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)), nil)
+	expiring, err = assetTransfer.GetExpiringAssets(transactionContext, 30)
+	require.NoError(t, err)
+	require.Len(t, expiring, 1)
+	require.Equal(t, "asset1", expiring[0].AssetID)
+	require.Equal(t, "SaveEquipment", expiring[0].Checkpoint)
+	require.True(t, expiring[0].ExpiresAt.Equal(time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)))
+	require.False(t, expiring[0].Expired)
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)), nil)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "org2admin")
+	commit()
+	require.EqualError(t, err, "the mandatory checkpoint SaveEquipment of asset asset1 has expired, it must be re-certified")
+
+	err = assetTransfer.ReCertify(transactionContext, "asset1", "SaveEquipment", 1)
+	commit()
+	require.NoError(t, err)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "org2admin")
+	commit()
+	require.NoError(t, err)
+}
+
+func TestDualInspectionReCertification(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	useClientIdentity(t, transactionContext, chaincodeStub)
+	setCaller(t, chaincodeStub, "org1admin")
+
+	credit, err := json.Marshal(&chaincode.Credit{})
+	require.NoError(t, err)
+	commit := setState(chaincodeStub, map[string][]byte{"org1admin": credit, "org2admin": credit, "org5admin": credit, "org5admin1": credit})
+
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetDualInspection(transactionContext, "B01", "org3admin")
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.SetCheckpointValidity(transactionContext, "B01", 30, true)
+	commit()
+	require.NoError(t, err)
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	commit()
+	require.NoError(t, err)
+
+	expiring, err := assetTransfer.GetExpiringAssets(transactionContext, 30)
+	require.NoError(t, err)
+	require.Len(t, expiring, 1)
+	require.True(t, expiring[0].ExpiresAt.Equal(time.Date(2023, 4, 14, 0, 0, 0, 0, time.UTC)), "a pending checkpoint counts from the asset's last result")
+	require.False(t, expiring[0].Expired)
+
+	now := time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC)
+	report := func(commonName string, mspID string, result int) error {
+		setCallerWithMSP(t, chaincodeStub, commonName, mspID)
+		chaincodeStub.GetTxTimestampReturns(timestamppb.New(now), nil)
+		err := assetTransfer.ReportInspection(transactionContext, "asset1", "B01", result)
+		commit()
+		return err
+	}
+	require.NoError(t, report("org1admin", "Org1MSP", 1))
+	require.NoError(t, report("org2admin", "Org2MSP", 1))
+	require.EqualError(t, report("org1admin", "Org1MSP", 1), "the inspection of B01 for asset asset1 is already agreed")
+
+	setCaller(t, chaincodeStub, "org1admin")
+	err = assetTransfer.ReCertify(transactionContext, "asset1", "B01", 1)
+	commit()
+	require.EqualError(t, err, "the checkpoint B01 requires dual inspection, use ReportInspection")
+
+	now = time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, report("org1admin", "Org1MSP", 1))
+	inspection, err := assetTransfer.ReadInspection(transactionContext, "asset1", "B01")
+	require.NoError(t, err)
+	require.Equal(t, "pending", inspection.Status)
+	require.Len(t, inspection.Reports, 1)
+
+	require.NoError(t, report("org2admin", "Org2MSP", 1))
+	setCaller(t, chaincodeStub, "org1admin")
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.True(t, asset.CheckedAt["B01"].Equal(time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)))
+	org5, err := assetTransfer.ReadCredit(transactionContext, "org5admin")
+	require.NoError(t, err)
+	require.Equal(t, float32(2), org5.Transaction)
+}
@@ -0,0 +1,254 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// statementObjectType is the composite key object type of the statements written by the chaincode.
+const statementObjectType = "statement"
+
+// CreditStatement mirrors the statement recorded by IssueCreditStatement.
+type CreditStatement struct {
+	ID          string    `json:"ID"`
+	Participant string    `json:"Participant"`
+	Grade       string    `json:"Grade"`
+	FinalScore  float32   `json:"FinalScore"`
+	Transaction float32   `json:"Transaction"`
+	Issuer      string    `json:"Issuer"`
+	IssuedAt    time.Time `json:"IssuedAt"`
+	Expiry      time.Time `json:"Expiry"`
+	Hash        string    `json:"Hash"`
+}
+
+// Block locates the statement on the ledger: the number of the block that committed the issuing
+// transaction, the hex encoded hash of that block's data and the data itself, so that the hash can be
+// recomputed and the statement found in the issuing transaction's write set.
+type Block struct {
+	Number   uint64   `json:"number"`
+	DataHash string   `json:"dataHash"`
+	Data     [][]byte `json:"data"`
+}
+
+// Payload is the signed part of a Document.
+type Payload struct {
+	Statement CreditStatement `json:"statement"`
+	Block     Block           `json:"block"`
+}
+
+// Document is the exported statement with the signature of the organization that exported it.
+type Document struct {
+	Payload     Payload `json:"payload"`
+	MSPID       string  `json:"mspId"`
+	Certificate string  `json:"certificate"`
+	Signature   string  `json:"signature"`
+}
+
+// signDocument signs the SHA-256 digest of the payload JSON.
+func signDocument(payload Payload, mspID string, certificatePEM []byte, sign identity.Sign) (*Document, error) {
+	digest, err := payloadDigest(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sign(digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign statement: %w", err)
+	}
+
+	return &Document{
+		Payload:     payload,
+		MSPID:       mspID,
+		Certificate: string(certificatePEM),
+		Signature:   base64.StdEncoding.EncodeToString(signature),
+	}, nil
+}
+
+// verifyDocument checks a document using only local data: the statement hash, the signer certificate
+// against the issuer certificate, the signature, the block data against its hash and the ledger hash,
+// the statement against the write of the issuing transaction in that block, and the expiry.
+func verifyDocument(document *Document, issuer *x509.Certificate, ledgerHash string, now time.Time) error {
+	statement := document.Payload.Statement
+
+	hash, err := statementHash(statement)
+	if err != nil {
+		return err
+	}
+	if hash != statement.Hash {
+		return fmt.Errorf("the statement hash %s does not match its content (%s)", statement.Hash, hash)
+	}
+
+	signer, err := identity.CertificateFromPEM([]byte(document.Certificate))
+	if err != nil {
+		return fmt.Errorf("failed to parse signer certificate: %w", err)
+	}
+	if !signer.Equal(issuer) {
+		roots := x509.NewCertPool()
+		roots.AddCert(issuer)
+		_, err = signer.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: statement.IssuedAt, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+		if err != nil {
+			return fmt.Errorf("the signer certificate is not issued by the issuer certificate: %w", err)
+		}
+	}
+
+	publicKey, ok := signer.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("the signer certificate must hold an ECDSA key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(document.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	digest, err := payloadDigest(document.Payload)
+	if err != nil {
+		return err
+	}
+	if !ecdsa.VerifyASN1(publicKey, digest, signature) {
+		return fmt.Errorf("the signature does not match the statement")
+	}
+
+	block := document.Payload.Block
+	dataHash := blockDataHash(block.Data)
+	if dataHash != block.DataHash {
+		return fmt.Errorf("the block data hash %s does not match the block data (%s)", block.DataHash, dataHash)
+	}
+	if ledgerHash != dataHash {
+		return fmt.Errorf("the block data hash %s does not match the ledger hash %s", dataHash, ledgerHash)
+	}
+
+	written, err := statementWrite(block.Data, statement.ID)
+	if err != nil {
+		return err
+	}
+	if written.Hash != statement.Hash {
+		return fmt.Errorf("the statement hash %s does not match the hash %s written by transaction %s", statement.Hash, written.Hash, statement.ID)
+	}
+
+	if now.After(statement.Expiry) {
+		return fmt.Errorf("the statement expired at %s", statement.Expiry.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func payloadDigest(payload Payload) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(payloadJSON)
+	return digest[:], nil
+}
+
+// blockDataHash returns the hex encoded SHA-256 digest of the concatenated block data, computed the
+// same way as the orderer.
+func blockDataHash(data [][]byte) string {
+	digest := sha256.Sum256(bytes.Join(data, nil))
+	return hex.EncodeToString(digest[:])
+}
+
+// statementWrite returns the statement written by the transaction with the given ID in the block data.
+func statementWrite(data [][]byte, txID string) (*CreditStatement, error) {
+	key := "\x00" + statementObjectType + "\x00" + txID + "\x00"
+
+	for _, envelopeBytes := range data {
+		envelope := &common.Envelope{}
+		payload := &common.Payload{}
+		channelHeader := &common.ChannelHeader{}
+		if err := unmarshal(envelopeBytes, envelope); err != nil {
+			return nil, err
+		}
+		if err := unmarshal(envelope.GetPayload(), payload); err != nil {
+			return nil, err
+		}
+		if err := unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+			return nil, err
+		}
+		if channelHeader.GetTxId() != txID {
+			continue
+		}
+
+		transaction := &peer.Transaction{}
+		if err := unmarshal(payload.GetData(), transaction); err != nil {
+			return nil, err
+		}
+		for _, action := range transaction.GetActions() {
+			actionPayload := &peer.ChaincodeActionPayload{}
+			responsePayload := &peer.ProposalResponsePayload{}
+			chaincodeAction := &peer.ChaincodeAction{}
+			readWriteSet := &rwset.TxReadWriteSet{}
+			if err := unmarshal(action.GetPayload(), actionPayload); err != nil {
+				return nil, err
+			}
+			if err := unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+				return nil, err
+			}
+			if err := unmarshal(responsePayload.GetExtension(), chaincodeAction); err != nil {
+				return nil, err
+			}
+			if err := unmarshal(chaincodeAction.GetResults(), readWriteSet); err != nil {
+				return nil, err
+			}
+
+			for _, namespace := range readWriteSet.GetNsRwset() {
+				kvReadWriteSet := &kvrwset.KVRWSet{}
+				if err := unmarshal(namespace.GetRwset(), kvReadWriteSet); err != nil {
+					return nil, err
+				}
+				for _, write := range kvReadWriteSet.GetWrites() {
+					if write.GetKey() != key || write.GetIsDelete() {
+						continue
+					}
+					var statement CreditStatement
+					if err := json.Unmarshal(write.GetValue(), &statement); err != nil {
+						return nil, fmt.Errorf("failed to parse the statement written by transaction %s: %w", txID, err)
+					}
+					return &statement, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("transaction %s does not write statement %s", txID, txID)
+	}
+
+	return nil, fmt.Errorf("transaction %s is not in the block", txID)
+}
+
+func unmarshal(data []byte, message proto.Message) error {
+	if err := proto.Unmarshal(data, message); err != nil {
+		return fmt.Errorf("failed to parse block data: %w", err)
+	}
+	return nil
+}
+
+// statementHash returns the hex encoded SHA-256 digest of the statement JSON with an empty Hash,
+// computed the same way as the chaincode.
+func statementHash(statement CreditStatement) (string, error) {
+	statement.Hash = ""
+
+	statementJSON, err := json.Marshal(statement)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(statementJSON)
+	return hex.EncodeToString(digest[:]), nil
+}
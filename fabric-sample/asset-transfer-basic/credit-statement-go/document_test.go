@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+var issuedAt = time.Date(2023, 3, 15, 12, 0, 0, 0, time.UTC)
+
+func TestVerifyDocument(t *testing.T) {
+	caKey, ca := newCertificate(t, "ca.org1.example.com", nil, nil)
+	signerKey, signer := newCertificate(t, "Admin@org1.example.com", ca, caKey)
+	_, other := newCertificate(t, "ca.org2.example.com", nil, nil)
+
+	statement := newStatement(t, "tx1")
+	data := [][]byte{
+		envelope(t, "tx0", "other", []byte("{}")),
+		envelope(t, "tx1", "\x00statement\x00tx1\x00", marshalJSON(t, statement)),
+	}
+	ledgerHash := blockDataHash(data)
+	payload := Payload{Statement: statement, Block: Block{Number: 7, DataHash: ledgerHash, Data: data}}
+
+	tests := []struct {
+		name       string
+		payload    func(payload Payload) Payload
+		document   func(document *Document)
+		issuer     *x509.Certificate
+		ledgerHash string
+		now        time.Time
+		err        string
+	}{
+		{name: "issued by the CA"},
+		{name: "signed by the issuer", issuer: signer},
+		{name: "expired", now: issuedAt.AddDate(0, 0, 91), err: "the statement expired at 2023-06-13T12:00:00Z"},
+		{name: "other issuer", issuer: other, err: "the signer certificate is not issued by the issuer certificate"},
+		{name: "no ledger hash", ledgerHash: "-", err: "does not match the ledger hash "},
+		{name: "other ledger hash", ledgerHash: blockDataHash(data[:1]), err: "does not match the ledger hash " + blockDataHash(data[:1])},
+		{
+			name: "tampered statement",
+			payload: func(payload Payload) Payload {
+				payload.Statement.Grade = "A"
+				return payload
+			},
+			err: "does not match its content",
+		},
+		{
+			name: "tampered signature",
+			document: func(document *Document) {
+				document.Payload.Block.Number = 8
+			},
+			err: "the signature does not match the statement",
+		},
+		{
+			name: "tampered block data",
+			payload: func(payload Payload) Payload {
+				payload.Block.Data = [][]byte{data[0], envelope(t, "tx1", "\x00statement\x00tx1\x00", []byte("{}"))}
+				return payload
+			},
+			err: "does not match the block data",
+		},
+		{
+			name: "restated statement",
+			payload: func(payload Payload) Payload {
+				payload.Statement = newStatement(t, "tx1")
+				payload.Statement.Grade = "A"
+				payload.Statement.Hash, _ = statementHash(payload.Statement)
+				return payload
+			},
+			err: "written by transaction tx1",
+		},
+		{
+			name: "other block",
+			payload: func(payload Payload) Payload {
+				payload.Block.Data = data[:1]
+				payload.Block.DataHash = blockDataHash(data[:1])
+				return payload
+			},
+			ledgerHash: blockDataHash(data[:1]),
+			err:        "transaction tx1 is not in the block",
+		},
+		{
+			name: "no statement write",
+			payload: func(payload Payload) Payload {
+				payload.Block.Data = [][]byte{envelope(t, "tx1", "\x00statement\x00tx2\x00", marshalJSON(t, statement))}
+				payload.Block.DataHash = blockDataHash(payload.Block.Data)
+				return payload
+			},
+			ledgerHash: blockDataHash([][]byte{envelope(t, "tx1", "\x00statement\x00tx2\x00", marshalJSON(t, statement))}),
+			err:        "transaction tx1 does not write statement tx1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signed := payload
+			if test.payload != nil {
+				signed = test.payload(payload)
+			}
+			document, err := signDocument(signed, "Org1MSP", encodeCertificate(signer), keySign(t, signerKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.document != nil {
+				test.document(document)
+			}
+
+			issuer, hash, now := ca, ledgerHash, issuedAt.Add(time.Hour)
+			if test.issuer != nil {
+				issuer = test.issuer
+			}
+			if test.ledgerHash == "-" {
+				hash = ""
+			} else if test.ledgerHash != "" {
+				hash = test.ledgerHash
+			}
+			if !test.now.IsZero() {
+				now = test.now
+			}
+
+			err = verifyDocument(roundTrip(t, document), issuer, hash, now)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestSignDocument(t *testing.T) {
+	key, certificate := newCertificate(t, "Admin@org1.example.com", nil, nil)
+	payload := Payload{Statement: newStatement(t, "tx1"), Block: Block{Number: 7, DataHash: blockDataHash(nil)}}
+
+	document, err := signDocument(payload, "Org1MSP", encodeCertificate(certificate), keySign(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.MSPID != "Org1MSP" || document.Certificate != string(encodeCertificate(certificate)) || document.Payload.Statement.ID != "tx1" {
+		t.Fatalf("document = %+v", document)
+	}
+
+	_, err = signDocument(payload, "Org1MSP", encodeCertificate(certificate), func(digest []byte) ([]byte, error) {
+		return nil, errors.New("HSM unavailable")
+	})
+	if err == nil || err.Error() != "failed to sign statement: HSM unavailable" {
+		t.Fatalf("error = %v, want the signing error", err)
+	}
+}
+
+func newStatement(t *testing.T, id string) CreditStatement {
+	t.Helper()
+	statement := CreditStatement{
+		ID:          id,
+		Participant: "org2admin",
+		Grade:       "B",
+		FinalScore:  0.75,
+		Transaction: 4,
+		Issuer:      "org1admin",
+		IssuedAt:    issuedAt,
+		Expiry:      issuedAt.AddDate(0, 0, 90),
+	}
+	hash, err := statementHash(statement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement.Hash = hash
+	return statement
+}
+
+// newCertificate creates a key and a certificate for it, issued by parent or self-signed.
+func newCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             issuedAt.AddDate(-1, 0, 0),
+		NotAfter:              issuedAt.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(certificateDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, certificate
+}
+
+func keySign(t *testing.T, key *ecdsa.PrivateKey) identity.Sign {
+	t.Helper()
+	sign, err := identity.NewPrivateKeySign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sign
+}
+
+// envelope builds a transaction envelope that writes a value to a key.
+func envelope(t *testing.T, txID, key string, value []byte) []byte {
+	t.Helper()
+	kvReadWriteSet := &kvrwset.KVRWSet{Writes: []*kvrwset.KVWrite{{Key: key, Value: value}}}
+	readWriteSet := &rwset.TxReadWriteSet{NsRwset: []*rwset.NsReadWriteSet{{Namespace: "basic", Rwset: marshal(t, kvReadWriteSet)}}}
+	chaincodeAction := &peer.ChaincodeAction{Results: marshal(t, readWriteSet)}
+	responsePayload := &peer.ProposalResponsePayload{Extension: marshal(t, chaincodeAction)}
+	actionPayload := &peer.ChaincodeActionPayload{Action: &peer.ChaincodeEndorsedAction{ProposalResponsePayload: marshal(t, responsePayload)}}
+	transaction := &peer.Transaction{Actions: []*peer.TransactionAction{{Payload: marshal(t, actionPayload)}}}
+	payload := &common.Payload{
+		Header: &common.Header{ChannelHeader: marshal(t, &common.ChannelHeader{ChannelId: "mychannel", TxId: txID})},
+		Data:   marshal(t, transaction),
+	}
+	return marshal(t, &common.Envelope{Payload: marshal(t, payload)})
+}
+
+// roundTrip returns the document as read back from its JSON file.
+func roundTrip(t *testing.T, document *Document) *Document {
+	t.Helper()
+	var read Document
+	if err := json.Unmarshal(marshalJSON(t, document), &read); err != nil {
+		t.Fatal(err)
+	}
+	return &read
+}
+
+func marshalJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	valueJSON, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return valueJSON
+}
+
+func marshal(t *testing.T, message proto.Message) []byte {
+	t.Helper()
+	messageBytes, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messageBytes
+}
@@ -0,0 +1,268 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// credit-statement exports a credit statement recorded by IssueCreditStatement as a signed JSON
+// document, and verifies such a document offline against the issuer certificate and the ledger hash of
+// the block that committed it.
+package main
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	mspID        = "Org1MSP"
+	cryptoPath   = "../../test-network/organizations/peerOrganizations/org1.example.com"
+	certPath     = cryptoPath + "/users/Admin@org1.example.com/msp/signcerts/cert.pem"
+	keyPath      = cryptoPath + "/users/Admin@org1.example.com/msp/keystore/"
+	tlsCertPath  = cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt"
+	peerEndpoint = "localhost:7051"
+	gatewayPeer  = "peer0.org1.example.com"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: credit-statement export|verify [flags]")
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "export":
+		export(os.Args[2:])
+	case "verify":
+		verify(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, expected export or verify\n", os.Args[1])
+		os.Exit(2)
+	}
+}
+
+// export reads a statement and the block that committed it from the network and writes the signed document.
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	txID := flags.String("statement", "", "id of the IssueCreditStatement transaction")
+	out := flags.String("out", "statement.json", "file to write the signed document to")
+	flags.Parse(args)
+
+	if *txID == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	sign := newSign()
+	gw, err := client.Connect(
+		newIdentity(),
+		client.WithSign(sign),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+	)
+	if err != nil {
+		panic(err)
+	}
+	defer gw.Close()
+
+	chaincodeName := "basic"
+	if ccname := os.Getenv("CHAINCODE_NAME"); ccname != "" {
+		chaincodeName = ccname
+	}
+
+	channelName := "mychannel"
+	if cname := os.Getenv("CHANNEL_NAME"); cname != "" {
+		channelName = cname
+	}
+
+	network := gw.GetNetwork(channelName)
+
+	fmt.Printf("\n--> Evaluate Transaction: ReadCreditStatement, function returns the statement %s\n", *txID)
+	statementJSON, err := network.GetContract(chaincodeName).EvaluateTransaction("ReadCreditStatement", *txID)
+	if err != nil {
+		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+	}
+
+	var statement CreditStatement
+	err = json.Unmarshal(statementJSON, &statement)
+	if err != nil {
+		panic(fmt.Errorf("failed to parse statement: %w", err))
+	}
+
+	fmt.Printf("\n--> Evaluate Transaction: GetBlockByTxID, function returns the block of transaction %s\n", *txID)
+	blockBytes, err := network.GetContract("qscc").EvaluateTransaction("GetBlockByTxID", channelName, *txID)
+	if err != nil {
+		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+	}
+
+	var block common.Block
+	err = proto.Unmarshal(blockBytes, &block)
+	if err != nil {
+		panic(fmt.Errorf("failed to parse block: %w", err))
+	}
+
+	payload := Payload{
+		Statement: statement,
+		Block: Block{
+			Number:   block.GetHeader().GetNumber(),
+			DataHash: hex.EncodeToString(block.GetHeader().GetDataHash()),
+			Data:     block.GetData().GetData(),
+		},
+	}
+
+	certificate, err := loadCertificate(certPath)
+	if err != nil {
+		panic(err)
+	}
+
+	document, err := signDocument(payload, mspID, encodeCertificate(certificate), sign)
+	if err != nil {
+		panic(err)
+	}
+
+	documentJSON, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.WriteFile(*out, documentJSON, 0644)
+	if err != nil {
+		panic(fmt.Errorf("failed to write document: %w", err))
+	}
+
+	fmt.Printf("*** Statement of %s (grade %s, block %d) written to %s\n", statement.Participant, statement.Grade, payload.Block.Number, *out)
+}
+
+// verify checks a signed document using only local files.
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	in := flags.String("in", "statement.json", "signed document to verify")
+	issuerPath := flags.String("issuer", "", "PEM certificate of the issuing organization or its CA")
+	ledgerHash := flags.String("ledger-hash", "", "hex encoded data hash of the block, as published by the network")
+	at := flags.String("at", "", "RFC3339 time to check the expiry against, defaults to now")
+	flags.Parse(args)
+
+	if *issuerPath == "" || *ledgerHash == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	documentJSON, err := os.ReadFile(*in)
+	if err != nil {
+		panic(fmt.Errorf("failed to read document: %w", err))
+	}
+
+	var document Document
+	err = json.Unmarshal(documentJSON, &document)
+	if err != nil {
+		panic(fmt.Errorf("failed to parse document: %w", err))
+	}
+
+	issuer, err := loadCertificate(*issuerPath)
+	if err != nil {
+		panic(err)
+	}
+
+	now := time.Now()
+	if *at != "" {
+		now, err = time.Parse(time.RFC3339, *at)
+		if err != nil {
+			panic(fmt.Errorf("invalid time %q: %w", *at, err))
+		}
+	}
+
+	err = verifyDocument(&document, issuer, *ledgerHash, now)
+	if err != nil {
+		fmt.Printf("*** Statement is NOT valid: %v\n", err)
+		os.Exit(1)
+	}
+
+	statement := document.Payload.Statement
+	fmt.Printf("*** Statement is valid: %s has grade %s as of block %d, signed by %s, expires %s\n",
+		statement.Participant, statement.Grade, document.Payload.Block.Number, document.MSPID, statement.Expiry.Format(time.RFC3339))
+}
+
+func encodeCertificate(certificate *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+}
+
+// newGrpcConnection creates a gRPC connection to the Gateway server.
+func newGrpcConnection() *grpc.ClientConn {
+	certificate, err := loadCertificate(tlsCertPath)
+	if err != nil {
+		panic(err)
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, gatewayPeer)
+
+	connection, err := grpc.Dial(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+	}
+
+	return connection
+}
+
+// newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
+func newIdentity() *identity.X509Identity {
+	certificate, err := loadCertificate(certPath)
+	if err != nil {
+		panic(err)
+	}
+
+	id, err := identity.NewX509Identity(mspID, certificate)
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+func loadCertificate(filename string) (*x509.Certificate, error) {
+	certificatePEM, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	return identity.CertificateFromPEM(certificatePEM)
+}
+
+// newSign creates a function that generates a digital signature from a message digest using a private key.
+func newSign() identity.Sign {
+	files, err := os.ReadDir(keyPath)
+	if err != nil {
+		panic(fmt.Errorf("failed to read private key directory: %w", err))
+	}
+	privateKeyPEM, err := os.ReadFile(path.Join(keyPath, files[0].Name()))
+	if err != nil {
+		panic(fmt.Errorf("failed to read private key file: %w", err))
+	}
+
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		panic(err)
+	}
+
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return sign
+}
@@ -1,28 +1,64 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"flag"
+	"log"
+	"os/signal"
 	"rest-api-go/web"
+	"syscall"
 
-	"github.com/Hsin1025/fabric-samples/asset-transfer-basic/rest-api-go/web"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
 func main() {
-	//Initialize setup for Org1
-	cryptoPath := "../../test-network/organizations/peerOrganizations/org1.example.com"
-	orgConfig := web.OrgSetup{
-		OrgName:      "Org1",
-		MSPID:        "Org1MSP",
-		CertPath:     cryptoPath + "/users/User1@org1.example.com/msp/signcerts/cert.pem",
-		KeyPath:      cryptoPath + "/users/User1@org1.example.com/msp/keystore/",
-		TLSCertPath:  cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt",
-		PeerEndpoint: "localhost:7051",
-		GatewayPeer:  "peer0.org1.example.com",
+	configPath := flag.String("config", "config.yaml", "configuration file")
+	flag.Parse()
+
+	config, err := web.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalln("Error loading configuration: ", err)
+	}
+
+	authenticators, err := web.LoadAuthenticators(config.AuthFile)
+	if err != nil {
+		log.Fatalln("Error loading authentication: ", err)
+	}
+
+	policy, err := web.LoadPolicy(config.PolicyFile)
+	if err == nil {
+		err = policy.Validate(&config.Profiles)
 	}
+	if err != nil {
+		log.Fatalln("Error loading policy: ", err)
+	}
+
+	metrics := web.NewMetrics(config.Operations.Functions)
+	pool, err := web.NewPool(&config.Profiles, config.Timeouts, config.Connection, metrics)
+	if err != nil {
+		log.Fatalln("Error initializing connections: ", err)
+	}
+	defer pool.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go pool.Monitor(ctx, config.ChannelID)
+	go metrics.LoadContractFunctions(ctx, func() (*client.Gateway, error) {
+		return pool.Gateway(config.Identities[0].Name)
+	}, config.ChannelID, config.ChaincodeName, config.Connection.HealthCheckInterval)
 
-	orgSetup, err := web.Initialize(orgConfig)
+	err = web.Serve(ctx, &web.Server{
+		ChannelID:      config.ChannelID,
+		ChaincodeName:  config.ChaincodeName,
+		Pool:           pool,
+		Transactions:   web.NewTracker(config.Callbacks),
+		Authenticators: authenticators,
+		Policy:         policy,
+		Metrics:        metrics,
+	}, config.Listen, config.Operations)
 	if err != nil {
-		fmt.Println("Error initializing setup for Org1: ", err)
+		pool.Close()
+		log.Fatalln("Error serving: ", err)
 	}
-	web.Serve(web.OrgSetup(*orgSetup))
 }
@@ -1,31 +1,154 @@
 package web
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
-// OrgSetup contains organization's config to interact with the network.
-type OrgSetup struct {
-	OrgName      string
-	MSPID        string
-	CryptoPath   string
-	CertPath     string
-	KeyPath      string
-	TLSCertPath  string
-	PeerEndpoint string
-	GatewayPeer  string
-	Gateway      client.Gateway
+// IdentityHeader names the wallet identity a request is signed with. Requests without it use the
+// first identity of their principal.
+const IdentityHeader = "X-Identity"
+
+// Server serves the REST API with the identities of a connection pool, to the principals of a policy.
+// Metrics must be the metrics the pool's connections report to.
+type Server struct {
+	ChannelID      string
+	ChaincodeName  string
+	Pool           *Pool
+	Transactions   *Tracker
+	Authenticators []Authenticator
+	Policy         *Policy
+	Metrics        *Metrics
+	streams        context.Context
+}
+
+// Serve starts http web server, and the operations server of the health and metrics endpoints, and
+// runs them until the context is done. It then stops accepting
+// connections, ends event streams and waits up to the shutdown timeout for in-flight requests and
+// for the commit status of asynchronous transactions, so that the pool can be closed afterwards.
+func Serve(ctx context.Context, server *Server, listen ListenConfig, operations OperationsConfig) error {
+	tlsConfig, err := listen.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	handle := func(route string, handler http.HandlerFunc) {
+		mux.HandleFunc(route, server.Metrics.instrument(route, handler))
+	}
+	handle("/query", server.authenticated(server.Query))
+	handle("/invoke", server.authenticated(server.Invoke))
+	handle("/assets", server.authenticated(server.Assets))
+	handle("/assets/", server.authenticated(server.Asset))
+	handle("/credits/", server.authenticated(server.Credit))
+	handle("/transactions/", server.authenticated(server.Transaction))
+	handle("/events/chaincode", server.authenticated(server.ChaincodeEvents))
+	handle("/events/blocks", server.authenticated(server.BlockEvents))
+	handle("/events/ws", server.authenticated(server.EventSocket))
+	handle("/offline/proposals", server.authenticated(server.OfflineProposal))
+	handle("/offline/endorse", server.authenticated(server.OfflineEndorse))
+	handle("/offline/submit", server.authenticated(server.OfflineSubmit))
+	handle("/offline/commit-status", server.authenticated(server.OfflineCommitStatus))
+	handle("/openapi.yaml", server.OpenAPI)
+
+	operationsMux := http.NewServeMux()
+	operationsMux.HandleFunc("/healthz", server.Metrics.instrument("/healthz", server.Healthz))
+	operationsMux.HandleFunc("/readyz", server.Metrics.instrument("/readyz", server.Readyz))
+	operationsMux.Handle("/metrics", server.Metrics.Handler())
+
+	streams, endStreams := context.WithCancel(context.Background())
+	defer endStreams()
+	server.streams = streams
+
+	httpServer := &http.Server{
+		Addr:              listen.Address,
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: listen.ReadHeaderTimeout,
+		ReadTimeout:       listen.ReadTimeout,
+		WriteTimeout:      listen.WriteTimeout,
+		IdleTimeout:       listen.IdleTimeout,
+	}
+	httpServer.RegisterOnShutdown(endStreams)
+	operationsServer := &http.Server{
+		Addr:              operations.Address,
+		Handler:           operationsMux,
+		ReadHeaderTimeout: listen.ReadHeaderTimeout,
+		ReadTimeout:       listen.ReadTimeout,
+		WriteTimeout:      listen.WriteTimeout,
+		IdleTimeout:       listen.IdleTimeout,
+	}
+
+	served := make(chan error, 2)
+	go func() {
+		log.Printf("Serving health and metrics (http://%s/)...\n", operations.Address)
+		served <- operationsServer.ListenAndServe()
+	}()
+	go func() {
+		if tlsConfig != nil {
+			log.Printf("Listening (https://%s/)...\n", listen.Address)
+			served <- httpServer.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Listening (http://%s/)...\n", listen.Address)
+			served <- httpServer.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-served:
+		httpServer.Close()
+		operationsServer.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down, draining in-flight requests...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), listen.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain in-flight requests: %w", err)
+	}
+	if err := server.Transactions.Wait(shutdownCtx); err != nil {
+		return err
+	}
+	if err := operationsServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop the operations server: %w", err)
+	}
+	log.Println("Shutdown complete")
+	return nil
+}
+
+// streamContext returns the context of an event stream, which ends with the request or when the
+// server shuts down.
+func (server *Server) streamContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	if server.streams != nil {
+		go func() {
+			select {
+			case <-server.streams.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
+
+// identity returns the name of the wallet identity a request is signed with.
+func (server *Server) identity(r *http.Request) (string, error) {
+	return principal(r).Identity(r.Header.Get(IdentityHeader))
 }
 
-// Serve starts http web server.
-func Serve(setups OrgSetup) {
-	http.HandleFunc("/query", setups.Query)
-	http.HandleFunc("/invoke", setups.Invoke)
-	fmt.Println("Listening (http://localhost:3000/)...")
-	if err := http.ListenAndServe(":3000", nil); err != nil {
-		fmt.Println(err)
+// gateway returns the Gateway connection of the identity a request is signed with.
+func (server *Server) gateway(r *http.Request) (*client.Gateway, error) {
+	name, err := server.identity(r)
+	if err != nil {
+		log.Printf("Denied %s %s: %v\n", r.Method, r.URL.Path, err)
+		return nil, err
 	}
+	return server.Pool.Gateway(name)
 }
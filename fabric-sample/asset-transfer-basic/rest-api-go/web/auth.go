@@ -0,0 +1,312 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of the authentication methods.
+const (
+	APIKeyHeader        = "X-Api-Key"
+	HMACKeyIDHeader     = "X-Auth-Key-Id"
+	HMACTimestampHeader = "X-Auth-Timestamp"
+	HMACNonceHeader     = "X-Auth-Nonce"
+	HMACSignatureHeader = "X-Auth-Signature"
+)
+
+// hmacMaxSkew is how far the timestamp of an HMAC-signed request may be from the server's clock.
+const hmacMaxSkew = 5 * time.Minute
+
+// maxSignedBodySize limits the body of an HMAC-signed request, which is read to check its signature.
+const maxSignedBodySize = 1 << 20
+
+// Authenticator authenticates a request with one method. It reports ok as false if the request
+// carries no credentials for the method, and an error if it carries invalid ones.
+type Authenticator interface {
+	Authenticate(r *http.Request) (principal string, ok bool, err error)
+}
+
+// AuthConfig configures the authentication methods the server accepts.
+type AuthConfig struct {
+	APIKeys  []APIKey   `json:"apiKeys"`
+	HMACKeys []HMACKey  `json:"hmacKeys"`
+	JWT      *JWTConfig `json:"jwt"`
+}
+
+// APIKey is a static API key, stored as the hex SHA-256 digest of the key.
+type APIKey struct {
+	Principal string `json:"principal"`
+	KeySHA256 string `json:"keySha256"`
+}
+
+// HMACKey is a shared secret for HMAC-signed requests. The secret is read from SecretEnv if it is set.
+type HMACKey struct {
+	Principal string `json:"principal"`
+	KeyID     string `json:"keyId"`
+	Secret    string `json:"secret"`
+	SecretEnv string `json:"secretEnv"`
+}
+
+// JWTConfig configures bearer tokens validated against a local JWKS file.
+type JWTConfig struct {
+	JWKSPath       string `json:"jwksPath"`
+	Issuer         string `json:"issuer"`
+	Audience       string `json:"audience"`
+	PrincipalClaim string `json:"principalClaim"`
+}
+
+// LoadAuthenticators reads an authentication file and creates an authenticator for each method it
+// configures. Relative paths in the file are resolved against its directory.
+func LoadAuthenticators(filename string) ([]Authenticator, error) {
+	configJSON, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authentication file: %w", err)
+	}
+
+	var config AuthConfig
+	err = json.Unmarshal(configJSON, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse authentication file %s: %w", filename, err)
+	}
+
+	var authenticators []Authenticator
+	if len(config.APIKeys) > 0 {
+		authenticator, err := newAPIKeyAuthenticator(config.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	if len(config.HMACKeys) > 0 {
+		authenticator, err := newHMACAuthenticator(config.HMACKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	if config.JWT != nil {
+		config.JWT.JWKSPath = resolvePath(filepath.Dir(filename), config.JWT.JWKSPath)
+		authenticator, err := newJWTAuthenticator(*config.JWT)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, fmt.Errorf("authentication file %s configures no authentication method", filename)
+	}
+	return authenticators, nil
+}
+
+type apiKeyAuthenticator struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	principal string
+	digest    []byte
+}
+
+func newAPIKeyAuthenticator(keys []APIKey) (*apiKeyAuthenticator, error) {
+	authenticator := &apiKeyAuthenticator{}
+	for _, key := range keys {
+		digest, err := hex.DecodeString(key.KeySHA256)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("the API key of %s is not a hex SHA-256 digest", key.Principal)
+		}
+		authenticator.keys = append(authenticator.keys, apiKey{principal: key.Principal, digest: digest})
+	}
+	return authenticator, nil
+}
+
+func (authenticator *apiKeyAuthenticator) Authenticate(r *http.Request) (string, bool, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return "", false, nil
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	for _, candidate := range authenticator.keys {
+		if subtle.ConstantTimeCompare(digest[:], candidate.digest) == 1 {
+			return candidate.principal, true, nil
+		}
+	}
+	return "", true, errors.New("unknown API key")
+}
+
+type hmacAuthenticator struct {
+	keys map[string]HMACKey
+
+	mu sync.Mutex
+	// nonces holds the nonces of accepted requests by key ID and nonce, until their timestamp
+	// leaves the allowed clock skew and the request would be refused anyway.
+	nonces map[string]time.Time
+}
+
+func newHMACAuthenticator(keys []HMACKey) (*hmacAuthenticator, error) {
+	authenticator := &hmacAuthenticator{
+		keys:   make(map[string]HMACKey),
+		nonces: make(map[string]time.Time),
+	}
+	for _, key := range keys {
+		if key.SecretEnv != "" {
+			key.Secret = os.Getenv(key.SecretEnv)
+		}
+		if key.KeyID == "" || key.Secret == "" {
+			return nil, fmt.Errorf("the HMAC key of %s needs a keyId and a secret", key.Principal)
+		}
+		authenticator.keys[key.KeyID] = key
+	}
+	return authenticator, nil
+}
+
+// Authenticate checks the signature of a request: the hex HMAC-SHA256 of its method, request URI,
+// timestamp, nonce and hex SHA-256 body digest, joined by newlines. A nonce may only be used once
+// per key.
+func (authenticator *hmacAuthenticator) Authenticate(r *http.Request) (string, bool, error) {
+	keyID := r.Header.Get(HMACKeyIDHeader)
+	if keyID == "" {
+		return "", false, nil
+	}
+
+	key, ok := authenticator.keys[keyID]
+	if !ok {
+		return "", true, fmt.Errorf("unknown HMAC key %s", keyID)
+	}
+
+	timestamp := r.Header.Get(HMACTimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", true, errors.New("invalid HMAC timestamp")
+	}
+	signedAt := time.Unix(seconds, 0)
+	skew := time.Since(signedAt)
+	if skew > hmacMaxSkew || skew < -hmacMaxSkew {
+		return "", true, errors.New("the HMAC timestamp is outside the allowed clock skew")
+	}
+
+	nonce := r.Header.Get(HMACNonceHeader)
+	if nonce == "" {
+		return "", true, errors.New("the HMAC nonce is missing")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize+1))
+	if err != nil {
+		return "", true, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(body) > maxSignedBodySize {
+		return "", true, errors.New("the signed request body is too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	signature, err := hex.DecodeString(r.Header.Get(HMACSignatureHeader))
+	if err != nil {
+		return "", true, errors.New("the HMAC signature is not hex")
+	}
+	if !hmac.Equal(signature, SignRequest(key.Secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)) {
+		return "", true, errors.New("invalid HMAC signature")
+	}
+
+	if !authenticator.useNonce(keyID, nonce, signedAt.Add(hmacMaxSkew)) {
+		return "", true, errors.New("the HMAC nonce has already been used")
+	}
+
+	return key.Principal, true, nil
+}
+
+// useNonce records a nonce of a key until it expires, and reports false if it is already recorded.
+func (authenticator *hmacAuthenticator) useNonce(keyID, nonce string, expires time.Time) bool {
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+
+	now := time.Now()
+	for seen, seenExpires := range authenticator.nonces {
+		if now.After(seenExpires) {
+			delete(authenticator.nonces, seen)
+		}
+	}
+
+	seen := keyID + "\n" + nonce
+	if _, ok := authenticator.nonces[seen]; ok {
+		return false
+	}
+	authenticator.nonces[seen] = expires
+	return true
+}
+
+// SignRequest computes the HMAC signature of a request.
+func SignRequest(secret, method, requestURI, timestamp, nonce string, body []byte) []byte {
+	bodyDigest := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, requestURI, timestamp, nonce, hex.EncodeToString(bodyDigest[:]))
+	return mac.Sum(nil)
+}
+
+type principalKey struct{}
+
+// authenticated wraps a handler so that it only runs for requests from a principal of the policy.
+func (server *Server) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, err := server.authenticate(r)
+		if err != nil {
+			log.Printf("Denied %s %s from %s: %v\n", r.Method, r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		principal, ok := server.Policy.Principal(name)
+		if !ok {
+			err = fmt.Errorf("%s has no policy", name)
+			log.Printf("Denied %s %s for %s: %v\n", r.Method, r.URL.Path, name, err)
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+
+		handler(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	}
+}
+
+func (server *Server) authenticate(r *http.Request) (string, error) {
+	for _, authenticator := range server.Authenticators {
+		name, ok, err := authenticator.Authenticate(r)
+		if ok {
+			return name, err
+		}
+	}
+	return "", errors.New("the request has no credentials")
+}
+
+// principal returns the principal a request was authenticated as.
+func principal(r *http.Request) *Principal {
+	return r.Context().Value(principalKey{}).(*Principal)
+}
+
+// authorize checks that the principal of a request may run a function of a chaincode on a channel,
+// and logs a denial.
+func (server *Server) authorize(r *http.Request, channel, chaincode, function string) error {
+	p := principal(r)
+	if p.Allows(channel, chaincode, function) {
+		return nil
+	}
+
+	err := fmt.Errorf("%s may not run %s of %s on %s", p.Name, function, chaincode, channel)
+	log.Printf("Denied %s %s: %v\n", r.Method, r.URL.Path, err)
+	return err
+}
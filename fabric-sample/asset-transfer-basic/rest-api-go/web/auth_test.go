@@ -0,0 +1,225 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	digest := sha256.Sum256([]byte("portal-key"))
+	authenticator, err := newAPIKeyAuthenticator([]APIKey{{Principal: "portal", KeySHA256: hex.EncodeToString(digest[:])}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		principal string
+		ok        bool
+		err       string
+	}{
+		{"no key", "", "", false, ""},
+		{"known key", "portal-key", "portal", true, ""},
+		{"unknown key", "other-key", "", true, "unknown API key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/query", nil)
+			if test.key != "" {
+				r.Header.Set(APIKeyHeader, test.key)
+			}
+			checkAuthentication(t, authenticator, r, test.principal, test.ok, test.err)
+		})
+	}
+
+	if _, err := newAPIKeyAuthenticator([]APIKey{{Principal: "portal", KeySHA256: "portal-key"}}); err == nil {
+		t.Error("a key that is not a SHA-256 digest must be rejected")
+	}
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	now := time.Now().Unix()
+	body := `{"function":"TransferAsset","args":["asset1","Christopher"]}`
+
+	tests := []struct {
+		name       string
+		keyID      string
+		timestamp  int64
+		nonce      string
+		signedURI  string
+		signedBody string
+		signature  string
+		principal  string
+		ok         bool
+		err        string
+	}{
+		{name: "no key", ok: false},
+		{name: "valid", keyID: "batch-1", timestamp: now, nonce: "n-valid", principal: "batch", ok: true},
+		{name: "within skew", keyID: "batch-1", timestamp: now - 240, nonce: "n-skew", principal: "batch", ok: true},
+		{name: "unknown key", keyID: "batch-2", timestamp: now, nonce: "n-unknown", ok: true, err: "unknown HMAC key batch-2"},
+		{name: "old timestamp", keyID: "batch-1", timestamp: now - 301, nonce: "n-old", ok: true, err: "the HMAC timestamp is outside the allowed clock skew"},
+		{name: "future timestamp", keyID: "batch-1", timestamp: now + 301, nonce: "n-future", ok: true, err: "the HMAC timestamp is outside the allowed clock skew"},
+		{name: "no nonce", keyID: "batch-1", timestamp: now, ok: true, err: "the HMAC nonce is missing"},
+		{name: "tampered body", keyID: "batch-1", timestamp: now, nonce: "n-body", signedBody: `{"function":"TransferAsset","args":["asset1","Max"]}`, ok: true, err: "invalid HMAC signature"},
+		{name: "tampered URI", keyID: "batch-1", timestamp: now, nonce: "n-uri", signedURI: "/invoke?channel=other", ok: true, err: "invalid HMAC signature"},
+		{name: "not hex", keyID: "batch-1", timestamp: now, nonce: "n-hex", signature: "not-hex", ok: true, err: "the HMAC signature is not hex"},
+		{name: "replayed nonce", keyID: "batch-1", timestamp: now, nonce: "n-valid", ok: true, err: "the HMAC nonce has already been used"},
+	}
+
+	authenticator, err := newHMACAuthenticator([]HMACKey{{Principal: "batch", KeyID: "batch-1", Secret: "s3cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/invoke?channel=mychannel", strings.NewReader(body))
+			if test.keyID != "" {
+				signedURI, signedBody := r.URL.RequestURI(), body
+				if test.signedURI != "" {
+					signedURI = test.signedURI
+				}
+				if test.signedBody != "" {
+					signedBody = test.signedBody
+				}
+				timestamp := strconv.FormatInt(test.timestamp, 10)
+				signature := test.signature
+				if signature == "" {
+					signature = hex.EncodeToString(SignRequest("s3cret", "POST", signedURI, timestamp, test.nonce, []byte(signedBody)))
+				}
+				r.Header.Set(HMACKeyIDHeader, test.keyID)
+				r.Header.Set(HMACTimestampHeader, timestamp)
+				r.Header.Set(HMACNonceHeader, test.nonce)
+				r.Header.Set(HMACSignatureHeader, signature)
+			}
+			checkAuthentication(t, authenticator, r, test.principal, test.ok, test.err)
+
+			if test.principal != "" {
+				read, err := io.ReadAll(r.Body)
+				if err != nil || string(read) != body {
+					t.Fatalf("body after authentication = %q, %v, want %q", read, err, body)
+				}
+			}
+		})
+	}
+
+	if _, err := newHMACAuthenticator([]HMACKey{{Principal: "batch", KeyID: "batch-1", SecretEnv: "HMAC_SECRET_UNSET"}}); err == nil {
+		t.Error("a key without a secret must be rejected")
+	}
+}
+
+func TestHMACNonceExpiry(t *testing.T) {
+	authenticator, err := newHMACAuthenticator([]HMACKey{{Principal: "batch", KeyID: "batch-1", Secret: "s3cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !authenticator.useNonce("batch-1", "n1", time.Now().Add(-time.Second)) {
+		t.Fatal("a new nonce must be accepted")
+	}
+	if !authenticator.useNonce("batch-2", "n2", time.Now().Add(hmacMaxSkew)) {
+		t.Fatal("a new nonce must be accepted")
+	}
+	if _, ok := authenticator.nonces["batch-1\nn1"]; ok {
+		t.Error("an expired nonce must be pruned")
+	}
+	if !authenticator.useNonce("batch-1", "n2", time.Now().Add(hmacMaxSkew)) {
+		t.Error("nonces must be tracked per key")
+	}
+	if authenticator.useNonce("batch-2", "n2", time.Now().Add(hmacMaxSkew)) {
+		t.Error("a nonce must only be accepted once")
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	digest := sha256.Sum256([]byte("portal-key"))
+	apiKeys, err := newAPIKeyAuthenticator([]APIKey{{Principal: "org1-portal", KeySHA256: hex.EncodeToString(digest[:])}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		Authenticators: []Authenticator{apiKeys},
+		Policy:         loadTestPolicy(t, `{"principals":[{"name":"org1-portal","identities":["org1-user1"]}]}`),
+	}
+
+	var called string
+	handler := server.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		called = principal(r).Name
+	})
+
+	tests := []struct {
+		name      string
+		key       string
+		status    int
+		principal string
+	}{
+		{"no credentials", "", http.StatusUnauthorized, ""},
+		{"unknown key", "other-key", http.StatusUnauthorized, ""},
+		{"known principal", "portal-key", http.StatusOK, "org1-portal"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called = ""
+			r := httptest.NewRequest("GET", "/query", nil)
+			if test.key != "" {
+				r.Header.Set(APIKeyHeader, test.key)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != test.status || called != test.principal {
+				t.Fatalf("status = %d, principal = %q, want %d, %q", w.Code, called, test.status, test.principal)
+			}
+		})
+	}
+
+	server.Authenticators = []Authenticator{staticAuthenticator("retired")}
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/query", nil))
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "retired has no policy") {
+		t.Fatalf("unknown principal: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+// staticAuthenticator authenticates every request as a principal.
+type staticAuthenticator string
+
+func (name staticAuthenticator) Authenticate(r *http.Request) (string, bool, error) {
+	return string(name), true, nil
+}
+
+func checkAuthentication(t *testing.T, authenticator Authenticator, r *http.Request, principal string, ok bool, want string) {
+	t.Helper()
+	gotPrincipal, gotOK, err := authenticator.Authenticate(r)
+	switch {
+	case gotOK != ok:
+		t.Fatalf("ok = %v, want %v", gotOK, ok)
+	case want == "" && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	case want != "" && (err == nil || err.Error() != want):
+		t.Fatalf("error = %v, want %q", err, want)
+	case gotPrincipal != principal:
+		t.Fatalf("principal = %q, want %q", gotPrincipal, principal)
+	}
+}
+
+func loadTestPolicy(t *testing.T, policyJSON string) *Policy {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(filename, []byte(policyJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
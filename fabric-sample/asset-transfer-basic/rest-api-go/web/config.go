@@ -0,0 +1,357 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OrgSetup contains organization's config to interact with the network. Peers lists the Gateway
+// peers in order of preference; PeerEndpoint, GatewayPeer and TLSCertPath describe a single peer
+// when Peers is empty, and TLSCertPath is the default of the peers that don't set one.
+type OrgSetup struct {
+	OrgName      string      `yaml:"name"`
+	MSPID        string      `yaml:"mspId"`
+	TLSCertPath  string      `yaml:"tlsCertPath"`
+	PeerEndpoint string      `yaml:"peerEndpoint"`
+	GatewayPeer  string      `yaml:"gatewayPeer"`
+	Peers        []PeerSetup `yaml:"peers"`
+}
+
+// PeerSetup is a Gateway peer of an organization.
+type PeerSetup struct {
+	Endpoint    string `yaml:"endpoint"`
+	GatewayPeer string `yaml:"gatewayPeer"`
+	TLSCertPath string `yaml:"tlsCertPath"`
+}
+
+// Identity is a wallet entry: a certificate and private key enrolled with one of the organizations.
+type Identity struct {
+	Name     string `yaml:"name"`
+	OrgName  string `yaml:"organization"`
+	CertPath string `yaml:"certPath"`
+	KeyPath  string `yaml:"keyPath"`
+}
+
+// Profiles is the set of organizations and identities the server loads at startup.
+type Profiles struct {
+	ChannelID     string     `yaml:"channel"`
+	ChaincodeName string     `yaml:"chaincode"`
+	Organizations []OrgSetup `yaml:"organizations"`
+	Identities    []Identity `yaml:"identities"`
+}
+
+// Config is the configuration of the REST server.
+type Config struct {
+	Profiles   `yaml:",inline"`
+	Listen     ListenConfig     `yaml:"listen"`
+	Operations OperationsConfig `yaml:"operations"`
+	Timeouts   GatewayTimeouts  `yaml:"timeouts"`
+	Connection ConnectionConfig `yaml:"connection"`
+	Callbacks  CallbackConfig   `yaml:"callbacks"`
+	AuthFile   string           `yaml:"authFile"`
+	PolicyFile string           `yaml:"policyFile"`
+}
+
+// ListenConfig configures the HTTP listener. The listener serves HTTPS when a certificate is set, and
+// verifies client certificates against ClientCAFile when ClientAuth is "request" or "require".
+type ListenConfig struct {
+	Address           string        `yaml:"address"`
+	CertFile          string        `yaml:"certFile"`
+	KeyFile           string        `yaml:"keyFile"`
+	ClientCAFile      string        `yaml:"clientCAFile"`
+	ClientAuth        string        `yaml:"clientAuth"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout"`
+}
+
+// OperationsConfig configures the listener of /healthz, /readyz and /metrics, which is kept apart from
+// the API so that it can stay on a private interface. Functions are the function names reported in
+// the Gateway metrics besides those in the contract metadata; others are reported as "other".
+type OperationsConfig struct {
+	Address   string   `yaml:"address"`
+	Functions []string `yaml:"functions"`
+}
+
+// GatewayTimeouts are the deadlines of the Gateway calls.
+type GatewayTimeouts struct {
+	Evaluate     time.Duration `yaml:"evaluate"`
+	Endorse      time.Duration `yaml:"endorse"`
+	Submit       time.Duration `yaml:"submit"`
+	CommitStatus time.Duration `yaml:"commitStatus"`
+}
+
+// ConnectionConfig configures the gRPC connections to the peers and how often their health is checked.
+type ConnectionConfig struct {
+	KeepaliveTime       time.Duration `yaml:"keepaliveTime"`
+	KeepaliveTimeout    time.Duration `yaml:"keepaliveTimeout"`
+	BackoffBaseDelay    time.Duration `yaml:"backoffBaseDelay"`
+	BackoffMaxDelay     time.Duration `yaml:"backoffMaxDelay"`
+	HealthCheckInterval time.Duration `yaml:"healthCheckInterval"`
+}
+
+// CallbackConfig restricts where the status of asynchronous transactions may be posted. AllowedHosts
+// are host names, or "*.domain" for any subdomain; callbacks are disabled while it is empty.
+// Callbacks are never posted to loopback, private or link-local addresses.
+type CallbackConfig struct {
+	AllowedHosts []string `yaml:"allowedHosts"`
+}
+
+// envOverrides are the environment variables that override a setting of the configuration file.
+var envOverrides = map[string]func(config *Config) *string{
+	"CHANNEL_NAME":            func(config *Config) *string { return &config.ChannelID },
+	"CHAINCODE_NAME":          func(config *Config) *string { return &config.ChaincodeName },
+	"REST_LISTEN_ADDRESS":     func(config *Config) *string { return &config.Listen.Address },
+	"REST_OPERATIONS_ADDRESS": func(config *Config) *string { return &config.Operations.Address },
+	"REST_TLS_CERT_FILE":      func(config *Config) *string { return &config.Listen.CertFile },
+	"REST_TLS_KEY_FILE":       func(config *Config) *string { return &config.Listen.KeyFile },
+	"REST_TLS_CLIENT_CA":      func(config *Config) *string { return &config.Listen.ClientCAFile },
+	"REST_TLS_CLIENT_AUTH":    func(config *Config) *string { return &config.Listen.ClientAuth },
+	"REST_AUTH_FILE":          func(config *Config) *string { return &config.AuthFile },
+	"REST_POLICY_FILE":        func(config *Config) *string { return &config.PolicyFile },
+}
+
+// LoadConfig reads a YAML configuration file. ${VAR} references in the file are expanded from the
+// environment, the variables in envOverrides replace their settings, and unset settings take their
+// defaults. Relative paths are resolved against the directory of the file.
+func LoadConfig(filename string) (*Config, error) {
+	configYAML, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	config := defaultConfig()
+	err = yaml.Unmarshal([]byte(os.ExpandEnv(string(configYAML))), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file %s: %w", filename, err)
+	}
+
+	for key, setting := range envOverrides {
+		if value, ok := os.LookupEnv(key); ok {
+			*setting(config) = value
+		}
+	}
+
+	dir := filepath.Dir(filename)
+	for i := range config.Organizations {
+		org := &config.Organizations[i]
+		org.TLSCertPath = resolvePath(dir, org.TLSCertPath)
+		if len(org.Peers) == 0 && org.PeerEndpoint != "" {
+			org.Peers = []PeerSetup{{Endpoint: org.PeerEndpoint, GatewayPeer: org.GatewayPeer}}
+		}
+		for j := range org.Peers {
+			peer := &org.Peers[j]
+			if peer.TLSCertPath == "" {
+				peer.TLSCertPath = org.TLSCertPath
+			} else {
+				peer.TLSCertPath = resolvePath(dir, peer.TLSCertPath)
+			}
+		}
+	}
+	for i := range config.Identities {
+		config.Identities[i].CertPath = resolvePath(dir, config.Identities[i].CertPath)
+		config.Identities[i].KeyPath = resolvePath(dir, config.Identities[i].KeyPath)
+	}
+	config.Listen.CertFile = resolvePath(dir, config.Listen.CertFile)
+	config.Listen.KeyFile = resolvePath(dir, config.Listen.KeyFile)
+	config.Listen.ClientCAFile = resolvePath(dir, config.Listen.ClientCAFile)
+	config.AuthFile = resolvePath(dir, config.AuthFile)
+	config.PolicyFile = resolvePath(dir, config.PolicyFile)
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", filename, err)
+	}
+	return config, nil
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Listen: ListenConfig{
+			Address:           ":3000",
+			ClientAuth:        "none",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Operations: OperationsConfig{
+			Address: "127.0.0.1:9480",
+		},
+		Timeouts: GatewayTimeouts{
+			Evaluate:     5 * time.Second,
+			Endorse:      15 * time.Second,
+			Submit:       5 * time.Second,
+			CommitStatus: 1 * time.Minute,
+		},
+		// peers reject keepalive pings more frequent than their keepalive.minInterval, 60s by default
+		Connection: ConnectionConfig{
+			KeepaliveTime:       time.Minute,
+			KeepaliveTimeout:    20 * time.Second,
+			BackoffBaseDelay:    time.Second,
+			BackoffMaxDelay:     30 * time.Second,
+			HealthCheckInterval: 10 * time.Second,
+		},
+		AuthFile:   "auth.json",
+		PolicyFile: "policy.json",
+	}
+}
+
+// validate reports every problem of the configuration in one error.
+func (config *Config) validate() error {
+	var problems []string
+	if err := config.Profiles.validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if config.ChannelID == "" {
+		problems = append(problems, "channel is required")
+	}
+	if config.ChaincodeName == "" {
+		problems = append(problems, "chaincode is required")
+	}
+
+	listen := config.Listen
+	if listen.Address == "" {
+		problems = append(problems, "listen.address is required")
+	}
+	if (listen.CertFile == "") != (listen.KeyFile == "") {
+		problems = append(problems, "listen.certFile and listen.keyFile must be set together")
+	}
+	switch listen.ClientAuth {
+	case "none":
+	case "request", "require":
+		if listen.CertFile == "" || listen.ClientCAFile == "" {
+			problems = append(problems, "listen.clientAuth needs TLS and listen.clientCAFile")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("listen.clientAuth must be none, request or require, not %q", listen.ClientAuth))
+	}
+	if listen.ReadHeaderTimeout < 0 || listen.ReadTimeout < 0 || listen.WriteTimeout < 0 || listen.IdleTimeout < 0 {
+		problems = append(problems, "listen timeouts must not be negative")
+	}
+	if listen.ShutdownTimeout <= 0 {
+		problems = append(problems, "listen.shutdownTimeout must be positive")
+	}
+
+	if config.Operations.Address == "" {
+		problems = append(problems, "operations.address is required")
+	} else if config.Operations.Address == listen.Address {
+		problems = append(problems, "operations.address must differ from listen.address")
+	}
+
+	timeouts := config.Timeouts
+	if timeouts.Evaluate <= 0 || timeouts.Endorse <= 0 || timeouts.Submit <= 0 || timeouts.CommitStatus <= 0 {
+		problems = append(problems, "timeouts must be positive")
+	}
+
+	connection := config.Connection
+	if connection.KeepaliveTime <= 0 || connection.KeepaliveTimeout <= 0 || connection.BackoffBaseDelay <= 0 ||
+		connection.BackoffMaxDelay < connection.BackoffBaseDelay || connection.HealthCheckInterval <= 0 {
+		problems = append(problems, "connection settings must be positive, with backoffMaxDelay at least backoffBaseDelay")
+	}
+
+	for _, host := range config.Callbacks.AllowedHosts {
+		if strings.TrimPrefix(host, "*.") == "" || strings.ContainsAny(host, "/:") {
+			problems = append(problems, fmt.Sprintf("callbacks.allowedHosts must be host names, not %q", host))
+		}
+	}
+
+	if config.AuthFile == "" {
+		problems = append(problems, "authFile is required")
+	}
+	if config.PolicyFile == "" {
+		problems = append(problems, "policyFile is required")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (profiles *Profiles) validate() error {
+	orgs := make(map[string]bool)
+	for _, org := range profiles.Organizations {
+		if org.OrgName == "" || org.MSPID == "" || len(org.Peers) == 0 {
+			return fmt.Errorf("organization %q needs a name, mspId and peers", org.OrgName)
+		}
+		for _, peer := range org.Peers {
+			if peer.Endpoint == "" || peer.TLSCertPath == "" {
+				return fmt.Errorf("a peer of organization %s needs an endpoint and tlsCertPath", org.OrgName)
+			}
+		}
+		if orgs[org.OrgName] {
+			return fmt.Errorf("organization %s is defined more than once", org.OrgName)
+		}
+		orgs[org.OrgName] = true
+	}
+
+	if len(profiles.Identities) == 0 {
+		return fmt.Errorf("no identities are defined")
+	}
+	identities := make(map[string]bool)
+	for _, id := range profiles.Identities {
+		if id.Name == "" {
+			return fmt.Errorf("an identity of organization %s has no name", id.OrgName)
+		}
+		if identities[id.Name] {
+			return fmt.Errorf("identity %s is defined more than once", id.Name)
+		}
+		if !orgs[id.OrgName] {
+			return fmt.Errorf("identity %s belongs to unknown organization %q", id.Name, id.OrgName)
+		}
+		identities[id.Name] = true
+	}
+
+	return nil
+}
+
+// TLSConfig returns the TLS configuration of the listener, or nil if it serves plain HTTP.
+func (listen ListenConfig) TLSConfig() (*tls.Config, error) {
+	if listen.CertFile == "" {
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(listen.CertFile, listen.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if listen.ClientAuth == "none" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(listen.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates in client CA file %s", listen.ClientCAFile)
+	}
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if listen.ClientAuth == "require" {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+func resolvePath(dir, filename string) string {
+	if filename == "" || filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(dir, filename)
+}
@@ -0,0 +1,147 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chaincodeResponse matches the message a peer reports for an error returned by the chaincode.
+var chaincodeResponse = regexp.MustCompile(`chaincode response (\d+), (.*)`)
+
+// writeError writes an error for a request the server rejects before it reaches the network.
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, ErrorResponse{Kind: "RequestError", Error: err.Error()})
+}
+
+// writeGatewayError writes an error returned by a Gateway call, with a status code that tells a
+// rejection by the chaincode apart from a failure to reach the network.
+func writeGatewayError(w http.ResponseWriter, err error) {
+	response := newErrorResponse(err)
+	writeJSON(w, errorStatusCode(err, response), response)
+}
+
+// writeCommitFailure writes the error for a transaction that was committed as invalid.
+func writeCommitFailure(w http.ResponseWriter, commitStatus *client.Status) {
+	err := &commitFailure{TransactionID: commitStatus.TransactionID, Code: commitStatus.Code}
+	writeGatewayError(w, err)
+}
+
+// commitFailure is a CommitError for a transaction whose commit status was obtained directly.
+type commitFailure struct {
+	TransactionID string
+	Code          peer.TxValidationCode
+}
+
+func (e *commitFailure) Error() string {
+	return fmt.Sprintf("transaction %s failed to commit with status code %d (%s)", e.TransactionID, int32(e.Code), e.Code)
+}
+
+func newErrorResponse(err error) ErrorResponse {
+	response := ErrorResponse{Kind: "EvaluateError", Error: err.Error()}
+
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	var commitErr *client.CommitError
+	var failure *commitFailure
+	switch {
+	case errors.As(err, &endorseErr):
+		response.Kind = "EndorseError"
+		response.TransactionID = endorseErr.TransactionID
+	case errors.As(err, &submitErr):
+		response.Kind = "SubmitError"
+		response.TransactionID = submitErr.TransactionID
+	case errors.As(err, &commitStatusErr):
+		response.Kind = "CommitStatusError"
+		response.TransactionID = commitStatusErr.TransactionID
+	case errors.As(err, &commitErr):
+		response.Kind = "CommitError"
+		response.TransactionID = commitErr.TransactionID
+		response.ValidationCode = commitErr.Code.String()
+		return response
+	case errors.As(err, &failure):
+		response.Kind = "CommitError"
+		response.TransactionID = failure.TransactionID
+		response.ValidationCode = failure.Code.String()
+		return response
+	}
+
+	// Any error that originates from a peer or orderer node external to the gateway has its details
+	// embedded within the gRPC status error.
+	statusErr := status.Convert(err)
+	response.GRPCStatus = statusErr.Code().String()
+
+	messages := []string{statusErr.Message()}
+	for _, detail := range statusErr.Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok {
+			response.Details = append(response.Details, ErrorDetail{
+				Address: detail.Address,
+				MSPID:   detail.MspId,
+				Message: detail.Message,
+			})
+			messages = append(messages, detail.Message)
+		}
+	}
+
+	for _, message := range messages {
+		if match := chaincodeResponse.FindStringSubmatch(message); match != nil {
+			response.ChaincodeStatus, _ = strconv.Atoi(match[1])
+			response.ChaincodeMessage = match[2]
+			break
+		}
+	}
+
+	return response
+}
+
+func errorStatusCode(err error, response ErrorResponse) int {
+	if response.Kind == "CommitError" {
+		switch response.ValidationCode {
+		case peer.TxValidationCode_MVCC_READ_CONFLICT.String(), peer.TxValidationCode_PHANTOM_READ_CONFLICT.String():
+			return http.StatusConflict
+		case peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE.String():
+			return http.StatusForbidden
+		default:
+			return http.StatusBadGateway
+		}
+	}
+
+	if response.ChaincodeMessage != "" {
+		switch {
+		case strings.Contains(response.ChaincodeMessage, "does not exist"):
+			return http.StatusNotFound
+		case strings.Contains(response.ChaincodeMessage, "already exists"):
+			return http.StatusConflict
+		default:
+			return http.StatusUnprocessableEntity
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return http.StatusForbidden
+	case codes.InvalidArgument, codes.NotFound:
+		return http.StatusBadRequest
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,278 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeGateway is a Gateway service that fails each call with its error, and otherwise endorses
+// transactions with its result and commits them with its validation code.
+type fakeGateway struct {
+	gateway.UnimplementedGatewayServer
+	evaluateErr     error
+	endorseErr      error
+	submitErr       error
+	commitStatusErr error
+	code            peer.TxValidationCode
+	result          []byte
+}
+
+func (fake *fakeGateway) Evaluate(ctx context.Context, request *gateway.EvaluateRequest) (*gateway.EvaluateResponse, error) {
+	if fake.evaluateErr != nil {
+		return nil, fake.evaluateErr
+	}
+	return &gateway.EvaluateResponse{Result: &peer.Response{Status: 200}}, nil
+}
+
+func (fake *fakeGateway) Endorse(ctx context.Context, request *gateway.EndorseRequest) (*gateway.EndorseResponse, error) {
+	if fake.endorseErr != nil {
+		return nil, fake.endorseErr
+	}
+	chaincodeAction, err := proto.Marshal(&peer.ChaincodeAction{Response: &peer.Response{Status: 200, Payload: fake.result}})
+	if err != nil {
+		return nil, err
+	}
+	responsePayload, err := proto.Marshal(&peer.ProposalResponsePayload{Extension: chaincodeAction})
+	if err != nil {
+		return nil, err
+	}
+	actionPayload, err := proto.Marshal(&peer.ChaincodeActionPayload{Action: &peer.ChaincodeEndorsedAction{ProposalResponsePayload: responsePayload}})
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := proto.Marshal(&peer.Transaction{Actions: []*peer.TransactionAction{{Payload: actionPayload}}})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(&common.Payload{Header: &common.Header{}, Data: transaction})
+	if err != nil {
+		return nil, err
+	}
+	return &gateway.EndorseResponse{PreparedTransaction: &common.Envelope{Payload: payload}}, nil
+}
+
+func (fake *fakeGateway) Submit(ctx context.Context, request *gateway.SubmitRequest) (*gateway.SubmitResponse, error) {
+	if fake.submitErr != nil {
+		return nil, fake.submitErr
+	}
+	return &gateway.SubmitResponse{}, nil
+}
+
+func (fake *fakeGateway) CommitStatus(ctx context.Context, request *gateway.SignedCommitStatusRequest) (*gateway.CommitStatusResponse, error) {
+	if fake.commitStatusErr != nil {
+		return nil, fake.commitStatusErr
+	}
+	return &gateway.CommitStatusResponse{Result: fake.code, BlockNumber: 7}, nil
+}
+
+// connectFake connects a Gateway to a fake Gateway service.
+func connectFake(t *testing.T, fake *fakeGateway) *client.Gateway {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	gateway.RegisterGatewayServer(grpcServer, fake)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	sign := func(digest []byte) ([]byte, error) { return []byte("signature"), nil }
+	gw, err := client.Connect(mspIdentity("Org1MSP"), client.WithSign(sign), client.WithClientConnection(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gw.Close() })
+	return gw
+}
+
+// peerError is a gRPC status error with the details of the peers that failed.
+func peerError(t *testing.T, code codes.Code, message string, details ...string) error {
+	t.Helper()
+	statusErr := status.New(code, message)
+	for i, detail := range details {
+		var err error
+		statusErr, err = statusErr.WithDetails(&gateway.ErrorDetail{
+			Address: fmt.Sprintf("peer%d.org1.example.com:7051", i),
+			MspId:   "Org1MSP",
+			Message: detail,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return statusErr.Err()
+}
+
+func TestGatewayErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		fake             fakeGateway
+		evaluate         bool
+		kind             string
+		grpcStatus       string
+		validationCode   string
+		chaincodeStatus  int
+		chaincodeMessage string
+		details          int
+		statusCode       int
+	}{
+		{
+			name:             "evaluate not found",
+			fake:             fakeGateway{evaluateErr: peerError(t, codes.Aborted, "evaluate call to endorser returned error: chaincode response 500, the asset asset1 does not exist", "chaincode response 500, the asset asset1 does not exist")},
+			evaluate:         true,
+			kind:             "EvaluateError",
+			grpcStatus:       "Aborted",
+			chaincodeStatus:  500,
+			chaincodeMessage: "the asset asset1 does not exist",
+			details:          1,
+			statusCode:       http.StatusNotFound,
+		},
+		{
+			name:             "endorse already exists",
+			fake:             fakeGateway{endorseErr: peerError(t, codes.Aborted, "failed to endorse transaction", "chaincode response 500, the asset asset1 already exists", "chaincode response 500, the asset asset1 already exists")},
+			kind:             "EndorseError",
+			grpcStatus:       "Aborted",
+			chaincodeStatus:  500,
+			chaincodeMessage: "the asset asset1 already exists",
+			details:          2,
+			statusCode:       http.StatusConflict,
+		},
+		{
+			name:             "endorse rejected",
+			fake:             fakeGateway{endorseErr: peerError(t, codes.Aborted, "failed to endorse transaction", "chaincode response 400, the new owner is the current owner")},
+			kind:             "EndorseError",
+			grpcStatus:       "Aborted",
+			chaincodeStatus:  400,
+			chaincodeMessage: "the new owner is the current owner",
+			details:          1,
+			statusCode:       http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "endorse denied",
+			fake:       fakeGateway{endorseErr: peerError(t, codes.PermissionDenied, "access denied")},
+			kind:       "EndorseError",
+			grpcStatus: "PermissionDenied",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "submit unavailable",
+			fake:       fakeGateway{submitErr: peerError(t, codes.Unavailable, "no orderers available")},
+			kind:       "SubmitError",
+			grpcStatus: "Unavailable",
+			statusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "commit status timeout",
+			fake:       fakeGateway{commitStatusErr: peerError(t, codes.DeadlineExceeded, "timed out")},
+			kind:       "CommitStatusError",
+			grpcStatus: "DeadlineExceeded",
+			statusCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "MVCC read conflict",
+			fake:           fakeGateway{code: peer.TxValidationCode_MVCC_READ_CONFLICT},
+			kind:           "CommitError",
+			validationCode: "MVCC_READ_CONFLICT",
+			statusCode:     http.StatusConflict,
+		},
+		{
+			name:           "endorsement policy failure",
+			fake:           fakeGateway{code: peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE},
+			kind:           "CommitError",
+			validationCode: "ENDORSEMENT_POLICY_FAILURE",
+			statusCode:     http.StatusForbidden,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := test.fake
+			contract := connectFake(t, &fake).GetNetwork("mychannel").GetContract("basic")
+
+			var err error
+			if test.evaluate {
+				_, err = contract.EvaluateTransaction("ReadAsset", "asset1")
+			} else {
+				_, err = contract.SubmitTransaction("CreateAsset", "asset1")
+			}
+			if err == nil {
+				t.Fatal("the call must fail")
+			}
+
+			response := newErrorResponse(err)
+			if response.Kind != test.kind || response.GRPCStatus != test.grpcStatus || response.ValidationCode != test.validationCode {
+				t.Fatalf("response = %+v, want kind %s, gRPC status %q, validation code %q", response, test.kind, test.grpcStatus, test.validationCode)
+			}
+			if !test.evaluate && response.TransactionID == "" {
+				t.Error("the response must have the transaction ID")
+			}
+			if response.ChaincodeStatus != test.chaincodeStatus || response.ChaincodeMessage != test.chaincodeMessage {
+				t.Errorf("chaincode status = %d %q, want %d %q", response.ChaincodeStatus, response.ChaincodeMessage, test.chaincodeStatus, test.chaincodeMessage)
+			}
+			if len(response.Details) != test.details {
+				t.Errorf("details = %+v, want %d", response.Details, test.details)
+			}
+			for _, detail := range response.Details {
+				if detail.MSPID != "Org1MSP" || detail.Address == "" || detail.Message == "" {
+					t.Errorf("incomplete detail %+v", detail)
+				}
+			}
+			if code := errorStatusCode(err, response); code != test.statusCode {
+				t.Errorf("status code = %d, want %d", code, test.statusCode)
+			}
+		})
+	}
+}
+
+func TestErrorStatusCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		kind       string
+		statusCode int
+	}{
+		{"phantom read", &commitFailure{TransactionID: "tx1", Code: peer.TxValidationCode_PHANTOM_READ_CONFLICT}, "CommitError", http.StatusConflict},
+		{"invalid commit", &commitFailure{TransactionID: "tx1", Code: peer.TxValidationCode_BAD_PAYLOAD}, "CommitError", http.StatusBadGateway},
+		{"context deadline", fmt.Errorf("evaluate: %w", context.DeadlineExceeded), "EvaluateError", http.StatusGatewayTimeout},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "unknown identity"), "EvaluateError", http.StatusForbidden},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad proposal"), "EvaluateError", http.StatusBadRequest},
+		{"not found", status.Error(codes.NotFound, "no such channel"), "EvaluateError", http.StatusBadRequest},
+		{"unknown", errors.New("connection reset"), "EvaluateError", http.StatusBadGateway},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := newErrorResponse(test.err)
+			if response.Kind != test.kind || response.Error != test.err.Error() {
+				t.Fatalf("response = %+v, want kind %s", response, test.kind)
+			}
+			if code := errorStatusCode(test.err, response); code != test.statusCode {
+				t.Fatalf("status code = %d, want %d", code, test.statusCode)
+			}
+		})
+	}
+
+	response := newErrorResponse(&commitFailure{TransactionID: "tx1", Code: peer.TxValidationCode_MVCC_READ_CONFLICT})
+	if response.TransactionID != "tx1" || response.ValidationCode != "MVCC_READ_CONFLICT" {
+		t.Fatalf("response = %+v", response)
+	}
+}
@@ -0,0 +1,290 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"golang.org/x/net/websocket"
+)
+
+// keepAliveInterval is how often an idle event stream sends a keep-alive.
+const keepAliveInterval = 15 * time.Second
+
+// ChaincodeEvent is a chaincode event as streamed to clients. Payloads that are not JSON are sent
+// as a string.
+type ChaincodeEvent struct {
+	BlockNumber   uint64      `json:"blockNumber"`
+	TransactionID string      `json:"transactionId"`
+	ChaincodeName string      `json:"chaincodeName"`
+	EventName     string      `json:"eventName"`
+	Payload       interface{} `json:"payload"`
+}
+
+// BlockEvent summarizes a committed block.
+type BlockEvent struct {
+	BlockNumber  uint64             `json:"blockNumber"`
+	Transactions []BlockTransaction `json:"transactions"`
+}
+
+// BlockTransaction is a transaction of a committed block with its validation code.
+type BlockTransaction struct {
+	TransactionID  string `json:"transactionId"`
+	ValidationCode string `json:"validationCode"`
+}
+
+// StreamMessage is a message on the WebSocket endpoint. ID can be passed back as lastEventId to resume.
+type StreamMessage struct {
+	ID    string      `json:"id"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+// ChaincodeEvents streams chaincode events as server-sent events.
+func (server *Server) ChaincodeEvents(w http.ResponseWriter, r *http.Request) {
+	server.serveEvents(w, r, "chaincode")
+}
+
+// BlockEvents streams committed blocks as server-sent events.
+func (server *Server) BlockEvents(w http.ResponseWriter, r *http.Request) {
+	server.serveEvents(w, r, "blocks")
+}
+
+// EventSocket streams chaincode or block events over a WebSocket, chosen by the type parameter.
+func (server *Server) EventSocket(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("type")
+	if kind == "" {
+		kind = "chaincode"
+	}
+
+	ctx, cancel := server.streamContext(r)
+	defer cancel()
+
+	events, err := server.openEvents(ctx, r, kind, r.URL.Query().Get("lastEventId"))
+	if err != nil {
+		writeEventsError(w, err)
+		return
+	}
+
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		// the client sends nothing; reading only detects that it went away
+		go func() {
+			var discard []byte
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+			cancel()
+		}()
+
+		for message := range events {
+			if err := websocket.JSON.Send(ws, message); err != nil {
+				return
+			}
+		}
+	}}.ServeHTTP(w, r)
+}
+
+func (server *Server) serveEvents(w http.ResponseWriter, r *http.Request, kind string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	ctx, cancel := server.streamContext(r)
+	defer cancel()
+
+	events, err := server.openEvents(ctx, r, kind, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		writeEventsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case message, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(message.Data)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", message.ID, message.Event, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// openEvents starts reading chaincode or block events with the identity of a request. Reading resumes
+// after the last event ID if one is given, and otherwise starts at the startBlock parameter, or at the
+// next block to be committed.
+func (server *Server) openEvents(ctx context.Context, r *http.Request, kind, lastEventID string) (<-chan StreamMessage, error) {
+	gateway, err := server.gateway(r)
+	if err != nil {
+		return nil, &requestError{http.StatusForbidden, err}
+	}
+	network := gateway.GetNetwork(server.ChannelID)
+
+	var start []client.ChaincodeEventsOption
+	switch {
+	case lastEventID != "":
+		checkpoint, err := parseEventID(lastEventID)
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, err}
+		}
+		start = append(start, client.WithCheckpoint(checkpoint))
+	case r.URL.Query().Get("startBlock") != "":
+		startBlock, err := strconv.ParseUint(r.URL.Query().Get("startBlock"), 10, 64)
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, fmt.Errorf("invalid startBlock: %w", err)}
+		}
+		start = append(start, client.WithStartBlock(startBlock))
+	}
+
+	switch kind {
+	case "chaincode":
+		chaincodeName := r.URL.Query().Get("chaincode")
+		if chaincodeName == "" {
+			chaincodeName = server.ChaincodeName
+		}
+		if err := server.authorize(r, server.ChannelID, chaincodeName, "ChaincodeEvents"); err != nil {
+			return nil, &requestError{http.StatusForbidden, err}
+		}
+		events, err := network.ChaincodeEvents(ctx, chaincodeName, start...)
+		if err != nil {
+			return nil, err
+		}
+		return streamChaincodeEvents(ctx, events), nil
+	case "blocks":
+		if err := server.authorize(r, server.ChannelID, "*", "BlockEvents"); err != nil {
+			return nil, &requestError{http.StatusForbidden, err}
+		}
+		var options []client.BlockEventsOption
+		for _, option := range start {
+			options = append(options, client.BlockEventsOption(option))
+		}
+		blocks, err := network.FilteredBlockEvents(ctx, options...)
+		if err != nil {
+			return nil, err
+		}
+		messages := make(chan StreamMessage)
+		go func() {
+			defer close(messages)
+			for block := range blocks {
+				event := BlockEvent{BlockNumber: block.Number, Transactions: []BlockTransaction{}}
+				for _, transaction := range block.FilteredTransactions {
+					event.Transactions = append(event.Transactions, BlockTransaction{
+						TransactionID:  transaction.Txid,
+						ValidationCode: transaction.TxValidationCode.String(),
+					})
+				}
+				// resuming from a block ID continues with the following block
+				message := StreamMessage{ID: strconv.FormatUint(block.Number, 10) + ":", Event: "block", Data: event}
+				if !send(ctx, messages, message) {
+					return
+				}
+			}
+		}()
+		return messages, nil
+	default:
+		return nil, &requestError{http.StatusBadRequest, fmt.Errorf("unknown event type %q, expected chaincode or blocks", kind)}
+	}
+}
+
+func streamChaincodeEvents(ctx context.Context, events <-chan *client.ChaincodeEvent) <-chan StreamMessage {
+	messages := make(chan StreamMessage)
+	go func() {
+		defer close(messages)
+		for event := range events {
+			var payload interface{} = string(event.Payload)
+			if json.Valid(event.Payload) {
+				payload = json.RawMessage(event.Payload)
+			}
+			message := StreamMessage{
+				ID:    fmt.Sprintf("%d:%s", event.BlockNumber, event.TransactionID),
+				Event: event.EventName,
+				Data: ChaincodeEvent{
+					BlockNumber:   event.BlockNumber,
+					TransactionID: event.TransactionID,
+					ChaincodeName: event.ChaincodeName,
+					EventName:     event.EventName,
+					Payload:       payload,
+				},
+			}
+			if !send(ctx, messages, message) {
+				return
+			}
+		}
+	}()
+	return messages
+}
+
+// send passes a message to the stream, or reports false if the stream was closed.
+func send(ctx context.Context, messages chan<- StreamMessage, message StreamMessage) bool {
+	select {
+	case messages <- message:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// parseEventID turns an event ID back into a checkpoint. Chaincode event IDs are
+// "<block>:<transaction ID>" and resume after that transaction; block event IDs are "<block>:" and
+// resume at the next block.
+func parseEventID(id string) (*client.InMemoryCheckpointer, error) {
+	block, transactionID, found := strings.Cut(id, ":")
+	blockNumber, err := strconv.ParseUint(block, 10, 64)
+	if !found || err != nil {
+		return nil, fmt.Errorf("invalid event ID %q", id)
+	}
+
+	checkpoint := new(client.InMemoryCheckpointer)
+	if transactionID == "" {
+		checkpoint.CheckpointBlock(blockNumber)
+	} else {
+		checkpoint.CheckpointTransaction(blockNumber, transactionID)
+	}
+	return checkpoint, nil
+}
+
+// requestError is an error in an event request, with the status code it is reported with.
+type requestError struct {
+	statusCode int
+	err        error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func writeEventsError(w http.ResponseWriter, err error) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		writeError(w, reqErr.statusCode, reqErr.err)
+		return
+	}
+	writeGatewayError(w, err)
+}
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"net/http"
+)
+
+// HealthResponse is the body of /healthz and /readyz.
+type HealthResponse struct {
+	Message string       `json:"message"`
+	Peers   []PeerStatus `json:"peers"`
+}
+
+// Healthz reports that the server is running, with the health of every peer.
+func (server *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, HealthResponse{Message: "ok", Peers: server.Pool.Status()})
+}
+
+// Readyz reports whether the server can take requests: every organization needs a peer that passed
+// its last health check.
+func (server *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{Message: "ok", Peers: server.Pool.Status()}
+	if err := server.Pool.Ready(); err != nil {
+		response.Message = err.Error()
+		writeJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -1,100 +1,482 @@
 package web
 
 import (
+	"context"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"path"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/protobuf/proto"
 )
 
-// Initialize the setup for the organization.
-func Initialize(setup OrgSetup) (*OrgSetup, error) {
-	log.Printf("Initializing connection for %s...\n", setup.OrgName)
-	clientConnection := setup.newGrpcConnection()
-	id := setup.newIdentity()
-	sign := setup.newSign()
-
-	gateway, err := client.Connect(
-		id,
-		client.WithSign(sign),
-		client.WithClientConnection(clientConnection),
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
-	if err != nil {
-		panic(err)
+// Pool holds a Gateway connection per identity and peer of its organization. Identities share the
+// gRPC connection to a peer, and requests go to the first healthy peer of their organization.
+type Pool struct {
+	mu         sync.Mutex
+	timeouts   GatewayTimeouts
+	connection ConnectionConfig
+	metrics    *Metrics
+	orgs       map[string]OrgSetup
+	identities map[string]Identity
+	peers      map[string]*peerConnection
+	peerOrder  []string
+	gateways   map[gatewayKey]*client.Gateway
+	closed     bool
+}
+
+// errPoolClosed is returned for Gateway connections requested after the pool was closed.
+var errPoolClosed = errors.New("the connection pool is closed")
+
+type gatewayKey struct {
+	identity string
+	endpoint string
+}
+
+// peerConnection is the gRPC connection to a peer and the result of its last health check.
+type peerConnection struct {
+	org       string
+	endpoint  string
+	conn      *grpc.ClientConn
+	checked   bool
+	healthy   bool
+	height    uint64
+	checkedAt time.Time
+	lastError string
+}
+
+// PeerStatus is the health of a peer as reported by /healthz and /readyz.
+type PeerStatus struct {
+	Organization string     `json:"organization"`
+	Endpoint     string     `json:"endpoint"`
+	State        string     `json:"state"`
+	Healthy      bool       `json:"healthy"`
+	BlockHeight  uint64     `json:"blockHeight,omitempty"`
+	CheckedAt    *time.Time `json:"checkedAt,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// NewPool loads every identity of the profiles and connects it to the Gateway of each peer of its
+// organization. The Gateway calls made on the connections are reported to the metrics.
+func NewPool(profiles *Profiles, timeouts GatewayTimeouts, connection ConnectionConfig, metrics *Metrics) (*Pool, error) {
+	pool := &Pool{
+		timeouts:   timeouts,
+		connection: connection,
+		metrics:    metrics,
+		orgs:       make(map[string]OrgSetup),
+		identities: make(map[string]Identity),
+		peers:      make(map[string]*peerConnection),
+		gateways:   make(map[gatewayKey]*client.Gateway),
+	}
+	for _, org := range profiles.Organizations {
+		pool.orgs[org.OrgName] = org
+		for _, peer := range org.Peers {
+			if _, err := pool.peerConnection(org, peer); err != nil {
+				pool.Close()
+				return nil, err
+			}
+		}
+	}
+
+	for _, id := range profiles.Identities {
+		pool.identities[id.Name] = id
+		if err := pool.connect(id); err != nil {
+			pool.Close()
+			return nil, err
+		}
 	}
-	setup.Gateway = *gateway
-	log.Println("Initialization complete")
-	return &setup, nil
+
+	return pool, nil
 }
 
-// newGrpcConnection creates a gRPC connection to the Gateway server.
-func (setup OrgSetup) newGrpcConnection() *grpc.ClientConn {
-	certificate, err := loadCertificate(setup.TLSCertPath)
+// connect creates the Gateway connections of an identity.
+func (pool *Pool) connect(id Identity) error {
+	org := pool.orgs[id.OrgName]
+
+	log.Printf("Initializing connection for %s of %s...\n", id.Name, org.OrgName)
+	x509Identity, err := newIdentity(org, id)
+	if err != nil {
+		return err
+	}
+	sign, err := newSign(id)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	certPool := x509.NewCertPool()
-	certPool.AddCert(certificate)
-	transportCredentials := credentials.NewClientTLSFromCert(certPool, setup.GatewayPeer)
+	for _, peer := range org.Peers {
+		clientConnection, err := pool.peerConnection(org, peer)
+		if err != nil {
+			return err
+		}
+
+		gateway, err := client.Connect(
+			x509Identity,
+			client.WithSign(sign),
+			client.WithClientConnection(clientConnection),
+			client.WithEvaluateTimeout(pool.timeouts.Evaluate),
+			client.WithEndorseTimeout(pool.timeouts.Endorse),
+			client.WithSubmitTimeout(pool.timeouts.Submit),
+			client.WithCommitStatusTimeout(pool.timeouts.CommitStatus),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to connect identity %s to %s: %w", id.Name, peer.Endpoint, err)
+		}
+		pool.gateways[gatewayKey{id.Name, peer.Endpoint}] = gateway
+	}
+
+	return nil
+}
+
+// Gateway returns the Gateway connection of an identity to the first healthy peer of its
+// organization, or to its first peer if none is healthy. Peers that were not checked yet count as
+// healthy.
+func (pool *Pool) Gateway(name string) (*client.Gateway, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.closed {
+		return nil, errPoolClosed
+	}
+
+	id, ok := pool.identities[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown identity %s", name)
+	}
+
+	peers := pool.orgs[id.OrgName].Peers
+	for _, peer := range peers {
+		connection := pool.peers[peer.Endpoint]
+		if !connection.checked || connection.healthy {
+			return pool.gateways[gatewayKey{name, peer.Endpoint}], nil
+		}
+	}
+	return pool.gateways[gatewayKey{name, peers[0].Endpoint}], nil
+}
+
+// Identity returns the wallet entry of an identity.
+func (pool *Pool) Identity(name string) (Identity, bool) {
+	id, ok := pool.identities[name]
+	return id, ok
+}
+
+// OfflineGateway connects an end user's identity to the first healthy peer of the organization with
+// its MSP ID. The Gateway has no signing implementation, so only proposals, transactions and commit
+// status requests the end user signed can be sent with it. Closing it leaves the peer connection open.
+func (pool *Pool) OfflineGateway(id identity.Identity) (*client.Gateway, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.closed {
+		return nil, errPoolClosed
+	}
+
+	for _, name := range pool.orgNames() {
+		org := pool.orgs[name]
+		if org.MSPID != id.MspID() {
+			continue
+		}
+
+		endpoint := org.Peers[0].Endpoint
+		for _, peer := range org.Peers {
+			if connection := pool.peers[peer.Endpoint]; !connection.checked || connection.healthy {
+				endpoint = peer.Endpoint
+				break
+			}
+		}
+
+		return client.Connect(
+			id,
+			client.WithClientConnection(pool.peers[endpoint].conn),
+			client.WithEvaluateTimeout(pool.timeouts.Evaluate),
+			client.WithEndorseTimeout(pool.timeouts.Endorse),
+			client.WithSubmitTimeout(pool.timeouts.Submit),
+			client.WithCommitStatusTimeout(pool.timeouts.CommitStatus),
+		)
+	}
+	return nil, fmt.Errorf("no organization with MSP ID %s", id.MspID())
+}
+
+// Monitor checks the health of every peer at the health check interval until the context is done.
+// A peer is healthy if its Gateway answers a query for the height of the channel, made with the first
+// identity of its organization.
+func (pool *Pool) Monitor(ctx context.Context, channelID string) {
+	ticker := time.NewTicker(pool.connection.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		pool.checkPeers(ctx, channelID)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (pool *Pool) checkPeers(ctx context.Context, channelID string) {
+	pool.mu.Lock()
+	connections := make([]*peerConnection, 0, len(pool.peerOrder))
+	for _, endpoint := range pool.peerOrder {
+		connections = append(connections, pool.peers[endpoint])
+	}
+	pool.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, connection := range connections {
+		wg.Add(1)
+		go func(connection *peerConnection) {
+			defer wg.Done()
+			height, err := pool.checkPeer(ctx, connection, channelID)
+			pool.recordCheck(connection, height, err)
+		}(connection)
+	}
+	wg.Wait()
+}
+
+func (pool *Pool) checkPeer(ctx context.Context, connection *peerConnection, channelID string) (uint64, error) {
+	// an idle connection only reconnects when it is used
+	if connection.conn.GetState() == connectivity.Idle {
+		connection.conn.Connect()
+	}
+
+	gateway := pool.probeGateway(connection)
+	if gateway == nil {
+		return 0, fmt.Errorf("no identity of %s to check the peer with", connection.org)
+	}
 
-	connection, err := grpc.Dial(setup.PeerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	ctx, cancel := context.WithTimeout(ctx, pool.timeouts.Evaluate)
+	defer cancel()
+
+	contract := gateway.GetNetwork(channelID).GetContract("qscc")
+	result, err := contract.EvaluateWithContext(ctx, "GetChainInfo", client.WithArguments(channelID))
 	if err != nil {
-		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+		return 0, err
 	}
 
-	return connection
+	info := &common.BlockchainInfo{}
+	if err := proto.Unmarshal(result, info); err != nil {
+		return 0, fmt.Errorf("failed to parse chain info: %w", err)
+	}
+	return info.GetHeight(), nil
 }
 
-// newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
-func (setup OrgSetup) newIdentity() *identity.X509Identity {
-	certificate, err := loadCertificate(setup.CertPath)
+// probeGateway returns the Gateway connection to a peer of the first identity of its organization.
+func (pool *Pool) probeGateway(connection *peerConnection) *client.Gateway {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for _, id := range pool.sortedIdentities() {
+		if id.OrgName == connection.org {
+			return pool.gateways[gatewayKey{id.Name, connection.endpoint}]
+		}
+	}
+	return nil
+}
+
+func (pool *Pool) sortedIdentities() []Identity {
+	identities := make([]Identity, 0, len(pool.identities))
+	for _, id := range pool.identities {
+		identities = append(identities, id)
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Name < identities[j].Name })
+	return identities
+}
+
+func (pool *Pool) recordCheck(connection *peerConnection, height uint64, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	wasHealthy := !connection.checked || connection.healthy
+	connection.checked = true
+	connection.checkedAt = time.Now().UTC()
+	connection.healthy = err == nil
 	if err != nil {
-		panic(err)
+		connection.lastError = err.Error()
+		if wasHealthy {
+			log.Printf("Peer %s of %s is unhealthy: %v\n", connection.endpoint, connection.org, err)
+		}
+		return
+	}
+
+	connection.lastError = ""
+	connection.height = height
+	if !wasHealthy {
+		log.Printf("Peer %s of %s is healthy again\n", connection.endpoint, connection.org)
+	}
+}
+
+// Status returns the health of every peer.
+func (pool *Pool) Status() []PeerStatus {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	statuses := make([]PeerStatus, 0, len(pool.peerOrder))
+	for _, endpoint := range pool.peerOrder {
+		connection := pool.peers[endpoint]
+		status := PeerStatus{
+			Organization: connection.org,
+			Endpoint:     endpoint,
+			State:        connection.conn.GetState().String(),
+			Healthy:      connection.healthy,
+			BlockHeight:  connection.height,
+			Error:        connection.lastError,
+		}
+		if connection.checked {
+			checkedAt := connection.checkedAt
+			status.CheckedAt = &checkedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Ready reports an error unless every organization has a healthy peer.
+func (pool *Pool) Ready() error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var unready []string
+	for _, name := range pool.orgNames() {
+		healthy := false
+		for _, peer := range pool.orgs[name].Peers {
+			if connection := pool.peers[peer.Endpoint]; connection.checked && connection.healthy {
+				healthy = true
+			}
+		}
+		if !healthy {
+			unready = append(unready, name)
+		}
 	}
 
-	id, err := identity.NewX509Identity(setup.MSPID, certificate)
+	if len(unready) > 0 {
+		return fmt.Errorf("no healthy peer for %s", strings.Join(unready, ", "))
+	}
+	return nil
+}
+
+func (pool *Pool) orgNames() []string {
+	names := make([]string, 0, len(pool.orgs))
+	for name := range pool.orgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close closes every Gateway and gRPC connection of the pool. Gateway connections can no longer be
+// obtained afterwards.
+func (pool *Pool) Close() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.closed = true
+
+	for key, gateway := range pool.gateways {
+		gateway.Close()
+		delete(pool.gateways, key)
+	}
+	for endpoint, connection := range pool.peers {
+		connection.conn.Close()
+		delete(pool.peers, endpoint)
+	}
+	pool.peerOrder = nil
+}
+
+// peerConnection returns the shared gRPC connection to a peer.
+func (pool *Pool) peerConnection(org OrgSetup, peer PeerSetup) (*grpc.ClientConn, error) {
+	if connection, ok := pool.peers[peer.Endpoint]; ok {
+		return connection.conn, nil
+	}
+
+	conn, err := newGrpcConnection(peer, pool.connection, pool.metrics)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return id
+	pool.peers[peer.Endpoint] = &peerConnection{org: org.OrgName, endpoint: peer.Endpoint, conn: conn}
+	pool.peerOrder = append(pool.peerOrder, peer.Endpoint)
+	return conn, nil
 }
 
-// newSign creates a function that generates a digital signature from a message digest using a private key.
-func (setup OrgSetup) newSign() identity.Sign {
-	files, err := ioutil.ReadDir(setup.KeyPath)
+// newGrpcConnection creates a gRPC connection to the Gateway server of a peer. The connection sends
+// keepalive pings, reconnects with exponential backoff and reports its Gateway calls to the metrics.
+func newGrpcConnection(peer PeerSetup, connection ConnectionConfig, metrics *Metrics) (*grpc.ClientConn, error) {
+	certificate, err := loadCertificate(peer.TLSCertPath)
 	if err != nil {
-		panic(fmt.Errorf("failed to read private key directory: %w", err))
+		return nil, err
 	}
-	privateKeyPEM, err := ioutil.ReadFile(path.Join(setup.KeyPath, files[0].Name()))
 
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, peer.GatewayPeer)
+
+	backoffConfig := backoff.DefaultConfig
+	backoffConfig.BaseDelay = connection.BackoffBaseDelay
+	backoffConfig.MaxDelay = connection.BackoffMaxDelay
+
+	conn, err := grpc.Dial(
+		peer.Endpoint,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                connection.KeepaliveTime,
+			Timeout:             connection.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoffConfig, MinConnectTimeout: 20 * time.Second}),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor()),
+	)
 	if err != nil {
-		panic(fmt.Errorf("failed to read private key file: %w", err))
+		return nil, fmt.Errorf("failed to create gRPC connection to %s: %w", peer.Endpoint, err)
 	}
 
-	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	return conn, nil
+}
+
+// newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
+func newIdentity(org OrgSetup, id Identity) (*identity.X509Identity, error) {
+	certificate, err := loadCertificate(id.CertPath)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	sign, err := identity.NewPrivateKeySign(privateKey)
+	return identity.NewX509Identity(org.MSPID, certificate)
+}
+
+// newSign creates a function that generates a digital signature from a message digest using a private key.
+func newSign(id Identity) (identity.Sign, error) {
+	files, err := ioutil.ReadDir(id.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key directory: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("private key directory %s is empty", id.KeyPath)
+	}
+	privateKeyPEM, err := ioutil.ReadFile(path.Join(id.KeyPath, files[0].Name()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
+	}
+
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return sign
+	return identity.NewPrivateKeySign(privateKey)
 }
 
 func loadCertificate(filename string) (*x509.Certificate, error) {
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"errors"
+	"testing"
+)
+
+// mspIdentity is an identity of an MSP without credentials.
+type mspIdentity string
+
+func (id mspIdentity) MspID() string       { return string(id) }
+func (id mspIdentity) Credentials() []byte { return nil }
+
+func TestPoolClosed(t *testing.T) {
+	pool := &Pool{
+		orgs:       map[string]OrgSetup{"Org1": {OrgName: "Org1", MSPID: "Org1MSP", Peers: []PeerSetup{{Endpoint: "localhost:7051"}}}},
+		identities: map[string]Identity{"org1-user1": {Name: "org1-user1", OrgName: "Org1"}},
+		peers:      map[string]*peerConnection{},
+	}
+	pool.Close()
+
+	if _, err := pool.Gateway("org1-user1"); !errors.Is(err, errPoolClosed) {
+		t.Fatalf("Gateway error = %v, want %v", err, errPoolClosed)
+	}
+	if _, err := pool.OfflineGateway(mspIdentity("Org1MSP")); !errors.Is(err, errPoolClosed) {
+		t.Fatalf("OfflineGateway error = %v, want %v", err, errPoolClosed)
+	}
+}
@@ -8,32 +8,61 @@ import (
 )
 
 // Invoke handles chaincode invoke requests.
-func (setup *OrgSetup) Invoke(w http.ResponseWriter, r *http.Request) {
+func (server *Server) Invoke(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Received Invoke request")
 	if err := r.ParseForm(); err != nil {
-		fmt.Fprintf(w, "ParseForm() err: %s", err)
+		writeError(w, http.StatusBadRequest, fmt.Errorf("ParseForm() err: %w", err))
 		return
 	}
 	chainCodeName := r.FormValue("chaincodeid")
 	channelID := r.FormValue("channelid")
 	function := r.FormValue("function")
 	args := r.Form["args"]
+	callback, err := server.Transactions.callbackURL(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
 	fmt.Printf("channel: %s, chaincode: %s, function: %s, args: %s\n", channelID, chainCodeName, function, args)
-	network := setup.Gateway.GetNetwork(channelID)
+	if err := server.authorize(r, channelID, chainCodeName, function); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	gateway, err := server.gateway(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	network := gateway.GetNetwork(channelID)
 	contract := network.GetContract(chainCodeName)
 	txn_proposal, err := contract.NewProposal(function, client.WithArguments(args...))
 	if err != nil {
-		fmt.Fprintf(w, "Error creating txn proposal: %s", err)
+		writeError(w, http.StatusBadRequest, fmt.Errorf("error creating txn proposal: %w", err))
 		return
 	}
 	txn_endorsed, err := txn_proposal.Endorse()
 	if err != nil {
-		fmt.Fprintf(w, "Error endorsing txn: %s", err)
+		writeGatewayError(w, err)
 		return
 	}
 	txn_committed, err := txn_endorsed.Submit()
 	if err != nil {
-		fmt.Fprintf(w, "Error submitting transaction: %s", err)
+		writeGatewayError(w, err)
+		return
+	}
+	if isAsync(r) {
+		transaction := server.Transactions.Track(principal(r).Name, txn_endorsed.Result(), txn_committed, callback)
+		w.Header().Set("Location", "/transactions/"+transaction.TransactionID)
+		writeJSON(w, http.StatusAccepted, transaction)
+		return
+	}
+	txn_status, err := txn_committed.Status()
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	if !txn_status.Successful {
+		writeCommitFailure(w, txn_status)
 		return
 	}
 	fmt.Fprintf(w, "Transaction ID : %s Response: %s", txn_committed.TransactionID(), txn_endorsed.Result())
@@ -0,0 +1,235 @@
+package web
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtLeeway is the clock skew allowed when checking the validity period of a token.
+const jwtLeeway = time.Minute
+
+type jwtAuthenticator struct {
+	config JWTConfig
+	keys   map[string]crypto.PublicKey
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func newJWTAuthenticator(config JWTConfig) (*jwtAuthenticator, error) {
+	if config.PrincipalClaim == "" {
+		config.PrincipalClaim = "sub"
+	}
+
+	jwksJSON, err := os.ReadFile(config.JWKSPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var jwks jsonWebKeySet
+	err = json.Unmarshal(jwksJSON, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %w", config.JWKSPath, err)
+	}
+
+	authenticator := &jwtAuthenticator{config: config, keys: make(map[string]crypto.PublicKey)}
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in %s: %w", jwk.Kid, config.JWKSPath, err)
+		}
+		authenticator.keys[jwk.Kid] = key
+	}
+	return authenticator, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+// Authenticate checks a bearer token. WebSocket requests may pass the token as the access_token
+// parameter, since browsers can't set headers on them.
+func (authenticator *jwtAuthenticator) Authenticate(r *http.Request) (string, bool, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == r.Header.Get("Authorization") {
+		token = ""
+	}
+	if token == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		return "", false, nil
+	}
+
+	claims, err := authenticator.verify(token)
+	if err != nil {
+		return "", true, fmt.Errorf("invalid token: %w", err)
+	}
+
+	principal, _ := claims[authenticator.config.PrincipalClaim].(string)
+	if principal == "" {
+		return "", true, fmt.Errorf("invalid token: no %s claim", authenticator.config.PrincipalClaim)
+	}
+	return principal, true, nil
+}
+
+func (authenticator *jwtAuthenticator) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	key, ok := authenticator.keys[header.Kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", header.Kid)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" {
+			return nil, fmt.Errorf("algorithm %s does not match the key", header.Alg)
+		}
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("bad signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(signature) != 64 {
+			return nil, fmt.Errorf("algorithm %s does not match the key", header.Alg)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return nil, errors.New("bad signature")
+		}
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, authenticator.checkClaims(claims)
+}
+
+func (authenticator *jwtAuthenticator) checkClaims(claims map[string]interface{}) error {
+	now := time.Now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("no exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return errors.New("the token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("the token is not valid yet")
+	}
+
+	if issuer := authenticator.config.Issuer; issuer != "" && claims["iss"] != issuer {
+		return fmt.Errorf("the token was not issued by %s", issuer)
+	}
+	if audience := authenticator.config.Audience; audience != "" && !hasAudience(claims["aud"], audience) {
+		return fmt.Errorf("the token is not for %s", audience)
+	}
+	return nil
+}
+
+// hasAudience reports whether an aud claim, a string or an array of strings, contains an audience.
+func hasAudience(claim interface{}, audience string) bool {
+	switch claim := claim.(type) {
+	case string:
+		return claim == audience
+	case []interface{}:
+		for _, value := range claim {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	segmentJSON, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(segmentJSON, v); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(bytes) == 0 {
+		return nil, errors.New("malformed key parameter")
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
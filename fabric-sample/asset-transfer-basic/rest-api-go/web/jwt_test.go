@@ -0,0 +1,190 @@
+package web
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := newJWTAuthenticator(JWTConfig{
+		JWKSPath: writeJWKS(t, rsaKey, ecKey),
+		Issuer:   "https://idp.example.com",
+		Audience: "asset-api",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Unix()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{"sub": "org1-portal", "iss": "https://idp.example.com", "aud": "asset-api", "exp": now + 300}
+		for name, value := range overrides {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+	rsaToken := signJWT(t, "RS256", "rsa-1", rsaKey, claims(nil))
+
+	tests := []struct {
+		name      string
+		token     string
+		principal string
+		err       string
+	}{
+		{"RS256", rsaToken, "org1-portal", ""},
+		{"ES256", signJWT(t, "ES256", "ec-1", ecKey, claims(nil)), "org1-portal", ""},
+		{"audience list", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"aud": []string{"other", "asset-api"}})), "org1-portal", ""},
+		{"expired within leeway", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"exp": now - 30})), "org1-portal", ""},
+		{"expired", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"exp": now - 120})), "", "invalid token: the token has expired"},
+		{"no exp", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"exp": nil})), "", "invalid token: no exp claim"},
+		{"not valid yet", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"nbf": now + 120})), "", "invalid token: the token is not valid yet"},
+		{"wrong issuer", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"iss": "https://evil.example.com"})), "", "invalid token: the token was not issued by https://idp.example.com"},
+		{"wrong audience", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"aud": "other"})), "", "invalid token: the token is not for asset-api"},
+		{"no principal", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]interface{}{"sub": nil})), "", "invalid token: no sub claim"},
+		{"unknown kid", signJWT(t, "RS256", "rsa-2", rsaKey, claims(nil)), "", `invalid token: unknown key "rsa-2"`},
+		{"ES256 with RSA key", signJWT(t, "ES256", "rsa-1", ecKey, claims(nil)), "", "invalid token: algorithm ES256 does not match the key"},
+		{"RS256 with EC key", signJWT(t, "RS256", "ec-1", rsaKey, claims(nil)), "", "invalid token: algorithm RS256 does not match the key"},
+		{"none", unsignedJWT(t, "none", "rsa-1", claims(nil)), "", "invalid token: algorithm none does not match the key"},
+		{"HS256", unsignedJWT(t, "HS256", "rsa-1", claims(nil)) + "c2lnbmF0dXJl", "", "invalid token: algorithm HS256 does not match the key"},
+		{"signed by another key", signJWT(t, "ES256", "ec-1", mustECKey(t), claims(nil)), "", "invalid token: bad signature"},
+		{"tampered claims", tamperJWT(t, rsaToken, claims(map[string]interface{}{"sub": "org2-portal"})), "", "invalid token: bad signature"},
+		{"malformed", "not.a-token", "", "invalid token: malformed token"},
+		{"malformed signature", rsaToken + "!", "", "invalid token: malformed signature"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/query", nil)
+			r.Header.Set("Authorization", "Bearer "+test.token)
+			checkAuthentication(t, authenticator, r, test.principal, true, test.err)
+		})
+	}
+
+	t.Run("no token", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/query?access_token="+rsaToken, nil)
+		checkAuthentication(t, authenticator, r, "", false, "")
+		r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+		checkAuthentication(t, authenticator, r, "", false, "")
+	})
+	t.Run("websocket", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/events?access_token="+rsaToken, nil)
+		r.Header.Set("Upgrade", "websocket")
+		checkAuthentication(t, authenticator, r, "org1-portal", true, "")
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  jsonWebKey
+	}{
+		{"key type", jsonWebKey{Kid: "k", Kty: "oct"}},
+		{"curve", jsonWebKey{Kid: "k", Kty: "EC", Crv: "P-384", X: "AQ", Y: "AQ"}},
+		{"point", jsonWebKey{Kid: "k", Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}},
+		{"modulus", jsonWebKey{Kid: "k", Kty: "RSA", N: "", E: "AQAB"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.jwk.publicKey(); err == nil {
+				t.Fatal("the key must be rejected")
+			}
+		})
+	}
+}
+
+func mustECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	jwks := jsonWebKeySet{Keys: []jsonWebKey{
+		{Kid: "rsa-1", Kty: "RSA", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+		{Kid: "ec-1", Kty: "EC", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)},
+	}}
+	jwksJSON, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(filename, jwksJSON, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func unsignedJWT(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	return encodeJWTSegment(t, jwtHeader{Alg: alg, Kid: kid}) + "." + encodeJWTSegment(t, claims) + "."
+}
+
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	signingInput := strings.TrimSuffix(unsignedJWT(t, alg, kid, claims), ".")
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tamperJWT replaces the claims of a token and keeps its signature.
+func tamperJWT(t *testing.T, token string, claims map[string]interface{}) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + encodeJWTSegment(t, claims) + "." + parts[2]
+}
+
+func encodeJWTSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	segmentJSON, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(segmentJSON)
+}
@@ -0,0 +1,443 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Transaction stages timed by the metrics, one per Gateway service call.
+const (
+	StageEvaluate     = "evaluate"
+	StageEndorse      = "endorse"
+	StageSubmit       = "submit"
+	StageCommitStatus = "commit_status"
+)
+
+// stageErrorKinds names the error a failed call of each stage returns, as reported in error responses.
+var stageErrorKinds = map[string]string{
+	StageEvaluate:     "EvaluateError",
+	StageEndorse:      "EndorseError",
+	StageSubmit:       "SubmitError",
+	StageCommitStatus: "CommitStatusError",
+}
+
+// gatewayStages maps the methods of the Gateway service to the stage they time.
+var gatewayStages = map[string]string{
+	"/gateway.Gateway/Evaluate":     StageEvaluate,
+	"/gateway.Gateway/Endorse":      StageEndorse,
+	"/gateway.Gateway/Submit":       StageSubmit,
+	"/gateway.Gateway/CommitStatus": StageCommitStatus,
+}
+
+// otherFunction labels the calls of functions that are not known to the metrics, so that clients
+// cannot create a time series per function name they make up.
+const otherFunction = "other"
+
+// latencyBuckets cover everything from a fast evaluate to a commit that waits for a slow block cut.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics collects the Prometheus metrics of the server: HTTP requests per route, and the calls the
+// server makes to the Gateway per channel, chaincode and function.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	httpInFlight  prometheus.Gauge
+	stageDuration *prometheus.HistogramVec
+	stageInFlight *prometheus.GaugeVec
+	transactions  *prometheus.HistogramVec
+	errors        *prometheus.CounterVec
+	committing    *prometheus.GaugeVec
+
+	mu           sync.Mutex
+	endorsed     map[string]endorsedTransaction
+	lastPrunedAt time.Time
+	functions    map[string]map[string]bool
+}
+
+// endorsedTransaction is a transaction between its endorsement and its commit status. Submit and
+// commit status requests only name the transaction, so they are labelled from its proposal.
+type endorsedTransaction struct {
+	labels     prometheus.Labels
+	endorsedAt time.Time
+	submitted  bool
+}
+
+// NewMetrics creates the server metrics in a registry of their own, along with the Go runtime and
+// process metrics. Gateway calls are labelled with the given functions of any chaincode, the
+// functions loaded from the contract metadata with LoadContractFunctions and the health check's
+// GetChainInfo; calls of other functions are labelled "other".
+func NewMetrics(functions []string) *Metrics {
+	stageLabels := []string{"channel", "chaincode", "function", "stage"}
+
+	metrics := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rest_api",
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rest_api",
+			Name:      "http_request_duration_seconds",
+			Help:      "Time to answer an HTTP request, by route and method. Event streams are not timed.",
+			Buckets:   latencyBuckets,
+		}, []string{"route", "method"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "rest_api",
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being answered, including open event streams.",
+		}),
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rest_api",
+			Name:      "gateway_stage_duration_seconds",
+			Help:      "Time of a Gateway call by channel, chaincode, function and stage: evaluate, endorse, submit or commit_status.",
+			Buckets:   latencyBuckets,
+		}, stageLabels),
+		stageInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "rest_api",
+			Name:      "gateway_stage_in_flight",
+			Help:      "Gateway calls waiting for a response, by channel, chaincode, function and stage.",
+		}, stageLabels),
+		transactions: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rest_api",
+			Name:      "transaction_duration_seconds",
+			Help:      "Time from the endorsement of a transaction to its commit status, by channel, chaincode, function and validation code.",
+			Buckets:   latencyBuckets,
+		}, []string{"channel", "chaincode", "function", "code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rest_api",
+			Name:      "gateway_errors_total",
+			Help:      "Failed transactions by channel, chaincode, function, error kind and gRPC status or validation code.",
+		}, []string{"channel", "chaincode", "function", "kind", "code"}),
+		committing: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "rest_api",
+			Name:      "transactions_in_flight",
+			Help:      "Transactions submitted to the orderer whose commit status is not known yet.",
+		}, []string{"channel", "chaincode", "function"}),
+		endorsed:  make(map[string]endorsedTransaction),
+		functions: map[string]map[string]bool{"": {}, "qscc": {"GetChainInfo": true}},
+	}
+	for _, function := range functions {
+		metrics.functions[""][function] = true
+	}
+
+	metrics.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.httpRequests,
+		metrics.httpDuration,
+		metrics.httpInFlight,
+		metrics.stageDuration,
+		metrics.stageInFlight,
+		metrics.transactions,
+		metrics.errors,
+		metrics.committing,
+	)
+	return metrics
+}
+
+// LoadContractFunctions reads the transaction functions of a chaincode from its contract metadata,
+// retrying at the given interval until it succeeds or the context is done. Functions of the default
+// contract are known both with and without the contract name.
+func (metrics *Metrics) LoadContractFunctions(ctx context.Context, gateway func() (*client.Gateway, error), channelID, chaincodeName string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := metrics.loadContractFunctions(gateway, channelID, chaincodeName)
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to read the contract metadata of %s, retrying: %v\n", chaincodeName, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (metrics *Metrics) loadContractFunctions(gateway func() (*client.Gateway, error), channelID, chaincodeName string) error {
+	connection, err := gateway()
+	if err != nil {
+		return err
+	}
+	metadataJSON, err := connection.GetNetwork(channelID).GetContract(chaincodeName).EvaluateTransaction("org.hyperledger.fabric:GetMetadata")
+	if err != nil {
+		return err
+	}
+	functions, err := contractFunctions(metadataJSON)
+	if err != nil {
+		return err
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.functions[chaincodeName] = functions
+	return nil
+}
+
+// contractFunctions returns the names the transaction functions of contract metadata are invoked with.
+func contractFunctions(metadataJSON []byte) (map[string]bool, error) {
+	var metadata struct {
+		Contracts map[string]struct {
+			Default      bool `json:"default"`
+			Transactions []struct {
+				Name string `json:"name"`
+			} `json:"transactions"`
+		} `json:"contracts"`
+	}
+	if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid contract metadata: %w", err)
+	}
+
+	functions := make(map[string]bool)
+	for name, contract := range metadata.Contracts {
+		for _, transaction := range contract.Transactions {
+			functions[name+":"+transaction.Name] = true
+			if contract.Default {
+				functions[transaction.Name] = true
+			}
+		}
+	}
+	return functions, nil
+}
+
+// function returns the label of a function of a chaincode: its name if it is known, or "other".
+func (metrics *Metrics) function(chaincode, function string) string {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	if metrics.functions[""][function] || metrics.functions[chaincode][function] {
+		return function
+	}
+	return otherFunction
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (metrics *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+}
+
+// instrument counts and times the requests of a route.
+func (metrics *Metrics) instrument(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		metrics.httpInFlight.Inc()
+		defer metrics.httpInFlight.Dec()
+
+		handler(recorder, r)
+
+		metrics.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		if !recorder.streaming {
+			metrics.httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		}
+	}
+}
+
+// statusRecorder remembers the status code a handler writes. Event streams flush the response or
+// hijack the connection, so it passes both through.
+type statusRecorder struct {
+	http.ResponseWriter
+	status    int
+	streaming bool
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Flush() {
+	recorder.streaming = true
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response does not support hijacking")
+	}
+	recorder.status = http.StatusSwitchingProtocols
+	recorder.streaming = true
+	return hijacker.Hijack()
+}
+
+// UnaryClientInterceptor times the Gateway calls made on a peer connection. It labels them with the
+// channel, chaincode and function of the proposal, so it covers every way the server submits a
+// transaction, offline-signed ones included.
+func (metrics *Metrics) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, request, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		stage, ok := gatewayStages[method]
+		if !ok {
+			return invoker(ctx, method, request, reply, cc, opts...)
+		}
+
+		transactionID, labels := metrics.labels(request)
+		stageLabels := withLabels(labels, prometheus.Labels{"stage": stage})
+
+		inFlight := metrics.stageInFlight.With(stageLabels)
+		inFlight.Inc()
+		start := time.Now()
+		err := invoker(ctx, method, request, reply, cc, opts...)
+		metrics.stageDuration.With(stageLabels).Observe(time.Since(start).Seconds())
+		inFlight.Dec()
+
+		if err != nil {
+			metrics.errors.With(withLabels(labels, prometheus.Labels{"kind": stageErrorKinds[stage], "code": status.Code(err).String()})).Inc()
+		}
+
+		switch stage {
+		case StageEndorse:
+			if err == nil {
+				metrics.endorse(transactionID, labels)
+			}
+		case StageSubmit:
+			if err == nil {
+				metrics.submit(transactionID)
+			}
+		case StageCommitStatus:
+			code := ""
+			if response, ok := reply.(*gateway.CommitStatusResponse); ok && err == nil {
+				code = response.GetResult().String()
+				if response.GetResult() != peer.TxValidationCode_VALID {
+					metrics.errors.With(withLabels(labels, prometheus.Labels{"kind": "CommitError", "code": code})).Inc()
+				}
+			}
+			metrics.commit(transactionID, code)
+		}
+		return err
+	}
+}
+
+// labels returns the transaction ID and the labels of a Gateway request.
+func (metrics *Metrics) labels(request interface{}) (string, prometheus.Labels) {
+	var transactionID, channel string
+	var proposal *peer.SignedProposal
+	switch request := request.(type) {
+	case *gateway.EvaluateRequest:
+		transactionID, channel, proposal = request.GetTransactionId(), request.GetChannelId(), request.GetProposedTransaction()
+	case *gateway.EndorseRequest:
+		transactionID, channel, proposal = request.GetTransactionId(), request.GetChannelId(), request.GetProposedTransaction()
+	case *gateway.SubmitRequest:
+		transactionID, channel = request.GetTransactionId(), request.GetChannelId()
+	case *gateway.SignedCommitStatusRequest:
+		statusRequest := &gateway.CommitStatusRequest{}
+		if err := proto.Unmarshal(request.GetRequest(), statusRequest); err == nil {
+			transactionID, channel = statusRequest.GetTransactionId(), statusRequest.GetChannelId()
+		}
+	}
+
+	if proposal != nil {
+		if _, chaincode, function, err := proposalTarget(proposal); err == nil {
+			return transactionID, prometheus.Labels{"channel": channel, "chaincode": chaincode, "function": metrics.function(chaincode, function)}
+		}
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if transaction, ok := metrics.endorsed[transactionID]; ok {
+		return transactionID, transaction.labels
+	}
+	// a transaction endorsed before the server restarted
+	return transactionID, prometheus.Labels{"channel": channel, "chaincode": "", "function": ""}
+}
+
+// endorse remembers the labels of an endorsed transaction for its submit and commit status calls.
+func (metrics *Metrics) endorse(transactionID string, labels prometheus.Labels) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	metrics.prune()
+	metrics.endorsed[transactionID] = endorsedTransaction{labels: labels, endorsedAt: time.Now()}
+}
+
+// submit counts a transaction accepted by the orderer as in flight until its commit status is known.
+func (metrics *Metrics) submit(transactionID string) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	transaction, ok := metrics.endorsed[transactionID]
+	if !ok || transaction.submitted {
+		return
+	}
+	transaction.submitted = true
+	metrics.endorsed[transactionID] = transaction
+	metrics.committing.With(transaction.labels).Inc()
+}
+
+// commit records the end of a transaction once its commit status is known, or could not be obtained
+// when the code is empty.
+func (metrics *Metrics) commit(transactionID, code string) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	transaction, ok := metrics.endorsed[transactionID]
+	if !ok {
+		return
+	}
+	delete(metrics.endorsed, transactionID)
+	if transaction.submitted {
+		metrics.committing.With(transaction.labels).Dec()
+	}
+	if code != "" {
+		metrics.transactions.With(withLabels(transaction.labels, prometheus.Labels{"code": code})).Observe(time.Since(transaction.endorsedAt).Seconds())
+	}
+}
+
+// prune forgets transactions whose commit status was never requested, such as offline-signed ones
+// the end user abandoned. The caller holds the lock.
+func (metrics *Metrics) prune() {
+	if time.Since(metrics.lastPrunedAt) < time.Minute {
+		return
+	}
+	metrics.lastPrunedAt = time.Now()
+
+	cutoff := time.Now().Add(-transactionRetention)
+	for id, transaction := range metrics.endorsed {
+		if transaction.endorsedAt.Before(cutoff) {
+			delete(metrics.endorsed, id)
+			if transaction.submitted {
+				metrics.committing.With(transaction.labels).Dec()
+			}
+		}
+	}
+}
+
+// withLabels returns the labels of a transaction with extra labels added.
+func withLabels(labels, extra prometheus.Labels) prometheus.Labels {
+	result := make(prometheus.Labels, len(labels)+len(extra))
+	for key, value := range labels {
+		result[key] = value
+	}
+	for key, value := range extra {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package web
+
+import "testing"
+
+func TestMetricsFunction(t *testing.T) {
+	metrics := NewMetrics([]string{"Ping"})
+
+	functions, err := contractFunctions([]byte(`{"contracts":{
+		"SmartContract":{"default":true,"transactions":[{"name":"ReadAsset"},{"name":"TransferAsset"}]},
+		"org.hyperledger.fabric":{"transactions":[{"name":"GetMetadata"}]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics.functions["basic"] = functions
+
+	tests := []struct {
+		chaincode string
+		function  string
+		want      string
+	}{
+		{"basic", "ReadAsset", "ReadAsset"},
+		{"basic", "SmartContract:TransferAsset", "SmartContract:TransferAsset"},
+		{"basic", "org.hyperledger.fabric:GetMetadata", "org.hyperledger.fabric:GetMetadata"},
+		{"basic", "GetMetadata", otherFunction},
+		{"basic", "x-8f3a2c", otherFunction},
+		{"other", "ReadAsset", otherFunction},
+		{"other", "Ping", "Ping"},
+		{"qscc", "GetChainInfo", "GetChainInfo"},
+	}
+	for _, test := range tests {
+		if got := metrics.function(test.chaincode, test.function); got != test.want {
+			t.Errorf("function(%q, %q) = %q, want %q", test.chaincode, test.function, got, test.want)
+		}
+	}
+
+	if _, err := contractFunctions([]byte("not json")); err == nil {
+		t.Error("invalid metadata must be rejected")
+	}
+}
@@ -0,0 +1,444 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// OfflineProposal handles POST /offline/proposals. It builds an unsigned proposal for the end user
+// whose certificate is in the request and returns it with the digest the user signs.
+func (server *Server) OfflineProposal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	var request OfflineProposalRequest
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.Channel == "" {
+		request.Channel = server.ChannelID
+	}
+	if request.Chaincode == "" {
+		request.Chaincode = server.ChaincodeName
+	}
+	if request.Function == "" {
+		writeError(w, http.StatusBadRequest, errors.New("function is required"))
+		return
+	}
+
+	certificate, err := identity.CertificateFromPEM([]byte(request.Certificate))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid certificate: %w", err))
+		return
+	}
+	id, err := identity.NewX509Identity(request.MSPID, certificate)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	gw, ok := server.offlineGateway(w, r, id, request.Channel, request.Chaincode, request.Function)
+	if !ok {
+		return
+	}
+	defer gw.Close()
+
+	proposal, err := gw.GetNetwork(request.Channel).GetContract(request.Chaincode).
+		NewProposal(request.Function, client.WithArguments(request.Args...))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	proposalBytes, err := proposal.Bytes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, OfflineSigningStep{
+		TransactionID: proposal.TransactionID(),
+		Bytes:         proposalBytes,
+		Digest:        proposal.Digest(),
+	})
+}
+
+// OfflineEndorse handles POST /offline/endorse. It endorses a signed proposal and returns the
+// transaction the end user signs next.
+func (server *Server) OfflineEndorse(w http.ResponseWriter, r *http.Request) {
+	var request OfflineSignedRequest
+	if !decodeSignedRequest(w, r, &request) {
+		return
+	}
+
+	proposed := &gateway.ProposedTransaction{}
+	header, err := proposalHeader(request.Bytes, proposed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	channel, chaincode, function, err := proposalTarget(proposed.GetProposal())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, certificate, err := creatorOf(header)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	signature, err := normalizeSignature(request.Signature, certificate)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	gw, ok := server.offlineGateway(w, r, id, channel, chaincode, function)
+	if !ok {
+		return
+	}
+	defer gw.Close()
+
+	proposal, err := gw.NewSignedProposal(request.Bytes, signature)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	transaction, err := proposal.Endorse()
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, OfflineSigningStep{
+		TransactionID: transaction.TransactionID(),
+		Result:        string(transaction.Result()),
+		Bytes:         transactionBytes,
+		Digest:        transaction.Digest(),
+	})
+}
+
+// OfflineSubmit handles POST /offline/submit. It submits a signed transaction to the orderer and
+// returns the commit status request the end user signs last.
+func (server *Server) OfflineSubmit(w http.ResponseWriter, r *http.Request) {
+	var request OfflineSignedRequest
+	if !decodeSignedRequest(w, r, &request) {
+		return
+	}
+
+	prepared := &gateway.PreparedTransaction{}
+	if err := proto.Unmarshal(request.Bytes, prepared); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid transaction: %w", err))
+		return
+	}
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(prepared.GetEnvelope().GetPayload(), payload); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid transaction: %w", err))
+		return
+	}
+	channel, chaincode, function, err := transactionTarget(payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, certificate, err := creatorOf(payload.GetHeader())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	signature, err := normalizeSignature(request.Signature, certificate)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	gw, ok := server.offlineGateway(w, r, id, channel, chaincode, function)
+	if !ok {
+		return
+	}
+	defer gw.Close()
+
+	transaction, err := gw.NewSignedTransaction(request.Bytes, signature)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	commit, err := transaction.Submit()
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	commitBytes, err := commit.Bytes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, OfflineSigningStep{
+		TransactionID: commit.TransactionID(),
+		Bytes:         commitBytes,
+		Digest:        commit.Digest(),
+	})
+}
+
+// OfflineCommitStatus handles POST /offline/commit-status. It waits for the commit status of a
+// transaction with a signed commit status request.
+func (server *Server) OfflineCommitStatus(w http.ResponseWriter, r *http.Request) {
+	var request OfflineSignedRequest
+	if !decodeSignedRequest(w, r, &request) {
+		return
+	}
+
+	signedRequest := &gateway.SignedCommitStatusRequest{}
+	statusRequest := &gateway.CommitStatusRequest{}
+	if err := proto.Unmarshal(request.Bytes, signedRequest); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid commit status request: %w", err))
+		return
+	}
+	if err := proto.Unmarshal(signedRequest.GetRequest(), statusRequest); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid commit status request: %w", err))
+		return
+	}
+	id, certificate, err := parseCreator(statusRequest.GetIdentity())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	signature, err := normalizeSignature(request.Signature, certificate)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	gw, ok := server.offlineGateway(w, r, id, statusRequest.GetChannelId(), "", "")
+	if !ok {
+		return
+	}
+	defer gw.Close()
+
+	commit, err := gw.NewSignedCommit(request.Bytes, signature)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	status, err := commit.Status()
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	response := TransactionStatus{
+		TransactionID:  status.TransactionID,
+		Status:         TransactionValid,
+		ValidationCode: status.Code.String(),
+		BlockNumber:    status.BlockNumber,
+	}
+	if !status.Successful {
+		response.Status = TransactionInvalid
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// offlineGateway checks that the principal of a request may act for an end user's MSP, and for a
+// function if one is given, and connects the end user's identity to a peer of its organization. The
+// Gateway has no signing implementation: every signature comes from the end user.
+func (server *Server) offlineGateway(w http.ResponseWriter, r *http.Request, id *identity.X509Identity, channel, chaincode, function string) (*client.Gateway, bool) {
+	p := principal(r)
+	if !p.AllowsOfflineMSP(id.MspID()) {
+		err := fmt.Errorf("%s may not sign offline for %s", p.Name, id.MspID())
+		log.Printf("Denied %s %s: %v\n", r.Method, r.URL.Path, err)
+		writeError(w, http.StatusForbidden, err)
+		return nil, false
+	}
+	if function != "" {
+		if err := server.authorize(r, channel, chaincode, function); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return nil, false
+		}
+	}
+
+	gw, err := server.Pool.OfflineGateway(id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return gw, true
+}
+
+func decodeSignedRequest(w http.ResponseWriter, r *http.Request, request *OfflineSignedRequest) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return false
+	}
+	if !decodeBody(w, r, request) {
+		return false
+	}
+	if len(request.Bytes) == 0 || len(request.Signature) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("bytes and signature are required"))
+		return false
+	}
+	return true
+}
+
+// proposalHeader parses a serialized proposal and returns its header.
+func proposalHeader(proposalBytes []byte, proposed *gateway.ProposedTransaction) (*common.Header, error) {
+	if err := proto.Unmarshal(proposalBytes, proposed); err != nil {
+		return nil, fmt.Errorf("invalid proposal: %w", err)
+	}
+	proposal := &peer.Proposal{}
+	if err := proto.Unmarshal(proposed.GetProposal().GetProposalBytes(), proposal); err != nil {
+		return nil, fmt.Errorf("invalid proposal: %w", err)
+	}
+	header := &common.Header{}
+	if err := proto.Unmarshal(proposal.GetHeader(), header); err != nil {
+		return nil, fmt.Errorf("invalid proposal: %w", err)
+	}
+	return header, nil
+}
+
+// proposalTarget returns the channel, chaincode and function a signed proposal invokes.
+func proposalTarget(signed *peer.SignedProposal) (string, string, string, error) {
+	proposal := &peer.Proposal{}
+	if err := proto.Unmarshal(signed.GetProposalBytes(), proposal); err != nil {
+		return "", "", "", fmt.Errorf("invalid proposal: %w", err)
+	}
+	header := &common.Header{}
+	if err := proto.Unmarshal(proposal.GetHeader(), header); err != nil {
+		return "", "", "", fmt.Errorf("invalid proposal: %w", err)
+	}
+	channel, err := channelOf(header)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	chaincode, function, err := invocationOf(proposal.GetPayload())
+	if err != nil {
+		return "", "", "", err
+	}
+	return channel, chaincode, function, nil
+}
+
+// transactionTarget returns the channel, chaincode and function a transaction envelope payload
+// invokes, from the proposal payload its endorsements were given for.
+func transactionTarget(payload *common.Payload) (string, string, string, error) {
+	channel, err := channelOf(payload.GetHeader())
+	if err != nil {
+		return "", "", "", err
+	}
+
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), transaction); err != nil {
+		return "", "", "", fmt.Errorf("invalid transaction: %w", err)
+	}
+	actions := transaction.GetActions()
+	if len(actions) != 1 {
+		return "", "", "", fmt.Errorf("the transaction has %d actions, want 1", len(actions))
+	}
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(actions[0].GetPayload(), actionPayload); err != nil {
+		return "", "", "", fmt.Errorf("invalid transaction action: %w", err)
+	}
+
+	chaincode, function, err := invocationOf(actionPayload.GetChaincodeProposalPayload())
+	if err != nil {
+		return "", "", "", err
+	}
+	return channel, chaincode, function, nil
+}
+
+// invocationOf returns the chaincode and function of a serialized chaincode proposal payload.
+func invocationOf(proposalPayload []byte) (string, string, error) {
+	payload := &peer.ChaincodeProposalPayload{}
+	invocation := &peer.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(proposalPayload, payload); err != nil {
+		return "", "", fmt.Errorf("invalid proposal payload: %w", err)
+	}
+	if err := proto.Unmarshal(payload.GetInput(), invocation); err != nil {
+		return "", "", fmt.Errorf("invalid proposal payload: %w", err)
+	}
+
+	spec := invocation.GetChaincodeSpec()
+	args := spec.GetInput().GetArgs()
+	if len(args) == 0 {
+		return "", "", errors.New("the proposal invokes no function")
+	}
+	return spec.GetChaincodeId().GetName(), string(args[0]), nil
+}
+
+func channelOf(header *common.Header) (string, error) {
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(header.GetChannelHeader(), channelHeader); err != nil {
+		return "", fmt.Errorf("invalid channel header: %w", err)
+	}
+	return channelHeader.GetChannelId(), nil
+}
+
+// creatorOf returns the identity that created a proposal or transaction.
+func creatorOf(header *common.Header) (*identity.X509Identity, *x509.Certificate, error) {
+	signatureHeader := &common.SignatureHeader{}
+	if err := proto.Unmarshal(header.GetSignatureHeader(), signatureHeader); err != nil {
+		return nil, nil, fmt.Errorf("invalid signature header: %w", err)
+	}
+	return parseCreator(signatureHeader.GetCreator())
+}
+
+func parseCreator(creator []byte) (*identity.X509Identity, *x509.Certificate, error) {
+	serialized := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(creator, serialized); err != nil {
+		return nil, nil, fmt.Errorf("invalid creator: %w", err)
+	}
+	certificate, err := identity.CertificateFromPEM(serialized.GetIdBytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid creator certificate: %w", err)
+	}
+	id, err := identity.NewX509Identity(serialized.GetMspid(), certificate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, certificate, nil
+}
+
+// normalizeSignature turns an ECDSA signature into the low-S ASN.1 DER form Fabric requires.
+// Signatures may also be given as the fixed-size r||s concatenation produced by WebCrypto and most
+// hardware tokens.
+func normalizeSignature(signature []byte, certificate *x509.Certificate) ([]byte, error) {
+	publicKey, ok := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return signature, nil
+	}
+
+	var parsed struct{ R, S *big.Int }
+	size := (publicKey.Curve.Params().BitSize + 7) / 8
+	if len(signature) == 2*size {
+		parsed.R = new(big.Int).SetBytes(signature[:size])
+		parsed.S = new(big.Int).SetBytes(signature[size:])
+	} else if rest, err := asn1.Unmarshal(signature, &parsed); err != nil || len(rest) > 0 {
+		return nil, errors.New("the signature is neither ASN.1 DER nor r||s")
+	}
+
+	order := publicKey.Curve.Params().N
+	if parsed.S.Cmp(new(big.Int).Rsh(order, 1)) > 0 {
+		parsed.S.Sub(order, parsed.S)
+	}
+	return asn1.Marshal(parsed)
+}
@@ -0,0 +1,219 @@
+package web
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNormalizeSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := &x509.Certificate{PublicKey: &key.PublicKey}
+	digest := sha256.Sum256([]byte("transaction"))
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := elliptic.P256().Params().N
+	halfOrder := new(big.Int).Rsh(order, 1)
+	lowS, highS := s, new(big.Int).Sub(order, s)
+	if s.Cmp(halfOrder) > 0 {
+		lowS, highS = highS, s
+	}
+
+	der := func(r, s *big.Int) []byte {
+		signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+	concatenated := func(r, s *big.Int) []byte {
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature
+	}
+
+	tests := []struct {
+		name      string
+		signature []byte
+		err       bool
+	}{
+		{name: "DER", signature: der(r, lowS)},
+		{name: "DER high-S", signature: der(r, highS)},
+		{name: "r||s", signature: concatenated(r, lowS)},
+		{name: "r||s high-S", signature: concatenated(r, highS)},
+		{name: "malformed DER", signature: der(r, lowS)[:20], err: true},
+		{name: "trailing bytes", signature: append(der(r, lowS), 0), err: true},
+		{name: "wrong length", signature: concatenated(r, lowS)[:63], err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized, err := normalizeSignature(test.signature, certificate)
+			if test.err {
+				if err == nil {
+					t.Fatal("the signature must be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(normalized, der(r, lowS)) {
+				t.Fatalf("signature = %x, want the low-S DER form %x", normalized, der(r, lowS))
+			}
+			if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], normalized) {
+				t.Fatal("the normalized signature does not verify")
+			}
+		})
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := []byte("rsa signature")
+	normalized, err := normalizeSignature(signature, &x509.Certificate{PublicKey: &rsaKey.PublicKey})
+	if err != nil || !bytes.Equal(normalized, signature) {
+		t.Fatalf("a non-ECDSA signature must be passed on unchanged: %x, %v", normalized, err)
+	}
+}
+
+func TestTransactionTarget(t *testing.T) {
+	channel, chaincode, function, err := transactionTarget(transactionPayload(t, "mychannel", "basic", []byte("TransferAsset"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel != "mychannel" || chaincode != "basic" || function != "TransferAsset" {
+		t.Fatalf("target = %s %s %s, want mychannel basic TransferAsset", channel, chaincode, function)
+	}
+
+	if _, _, _, err := transactionTarget(transactionPayload(t, "mychannel", "basic", nil, nil)); err == nil {
+		t.Error("a transaction without a function must be rejected")
+	}
+
+	payload := transactionPayload(t, "mychannel", "basic", []byte("TransferAsset"), nil)
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.Data, transaction); err != nil {
+		t.Fatal(err)
+	}
+	transaction.Actions = append(transaction.Actions, transaction.Actions[0])
+	payload.Data = marshal(t, transaction)
+	if _, _, _, err := transactionTarget(payload); err == nil {
+		t.Error("a transaction with several actions must be rejected")
+	}
+}
+
+func TestOfflineSubmitAuthorization(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "user1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creator := marshal(t, &msp.SerializedIdentity{
+		Mspid:   "Org2MSP",
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateDER}),
+	})
+
+	server := &Server{
+		Authenticators: []Authenticator{staticAuthenticator("wallet-relay")},
+		Policy: loadTestPolicy(t, `{"principals":[{"name":"wallet-relay","offlineMspIds":["Org2MSP"],"allow":[
+			{"channel":"mychannel","chaincode":"basic","functions":["ReadAsset","TransferAsset"]}]}]}`),
+	}
+	handler := server.authenticated(server.OfflineSubmit)
+
+	tests := []struct {
+		name      string
+		chaincode string
+		function  string
+		want      string
+	}{
+		{"function not allowed", "basic", "DeleteAsset", "wallet-relay may not run DeleteAsset of basic on mychannel"},
+		{"chaincode not allowed", "other", "TransferAsset", "wallet-relay may not run TransferAsset of other on mychannel"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload := transactionPayload(t, "mychannel", test.chaincode, []byte(test.function), creator)
+			prepared := &gateway.PreparedTransaction{Envelope: &common.Envelope{Payload: marshal(t, payload)}}
+			body, err := json.Marshal(OfflineSignedRequest{Bytes: marshal(t, prepared), Signature: bytes.Repeat([]byte{1}, 64)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest("POST", "/offline/submit", bytes.NewReader(body)))
+			if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), test.want) {
+				t.Fatalf("status = %d, body = %s, want %d %q", w.Code, w.Body.String(), http.StatusForbidden, test.want)
+			}
+		})
+	}
+}
+
+// transactionPayload builds the payload of a transaction envelope that invokes a function of a
+// chaincode, created by a serialized identity.
+func transactionPayload(t *testing.T, channel, chaincode string, function, creator []byte) *common.Payload {
+	t.Helper()
+	var args [][]byte
+	if function != nil {
+		args = append(args, function, []byte("asset1"))
+	}
+	invocation := &peer.ChaincodeInvocationSpec{ChaincodeSpec: &peer.ChaincodeSpec{
+		ChaincodeId: &peer.ChaincodeID{Name: chaincode},
+		Input:       &peer.ChaincodeInput{Args: args},
+	}}
+	actionPayload := &peer.ChaincodeActionPayload{
+		ChaincodeProposalPayload: marshal(t, &peer.ChaincodeProposalPayload{Input: marshal(t, invocation)}),
+	}
+	transaction := &peer.Transaction{Actions: []*peer.TransactionAction{{Payload: marshal(t, actionPayload)}}}
+
+	return &common.Payload{
+		Header: &common.Header{
+			ChannelHeader:   marshal(t, &common.ChannelHeader{ChannelId: channel, TxId: "tx1"}),
+			SignatureHeader: marshal(t, &common.SignatureHeader{Creator: creator}),
+		},
+		Data: marshal(t, transaction),
+	}
+}
+
+func marshal(t *testing.T, message proto.Message) []byte {
+	t.Helper()
+	messageBytes, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messageBytes
+}
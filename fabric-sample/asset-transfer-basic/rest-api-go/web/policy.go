@@ -0,0 +1,139 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Policy maps principals to the identities they sign with and the transactions they may run.
+type Policy struct {
+	Principals []Principal `json:"principals"`
+	principals map[string]*Principal
+}
+
+// Principal is an authenticated caller. The first identity is used when a request doesn't choose one.
+// OfflineMSPIDs lists the MSPs whose end users the principal may relay offline-signed transactions for.
+type Principal struct {
+	Name          string   `json:"name"`
+	Identities    []string `json:"identities"`
+	Allow         []Rule   `json:"allow"`
+	OfflineMSPIDs []string `json:"offlineMspIds"`
+}
+
+// Rule allows a set of functions of a chaincode on a channel. "*" matches any channel, chaincode or
+// function. Event streams are checked as the ChaincodeEvents function of their chaincode, and block
+// streams as the BlockEvents function of chaincode "*".
+type Rule struct {
+	Channel   string   `json:"channel"`
+	Chaincode string   `json:"chaincode"`
+	Functions []string `json:"functions"`
+}
+
+// LoadPolicy reads a policy file.
+func LoadPolicy(filename string) (*Policy, error) {
+	policyJSON, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy Policy
+	err = json.Unmarshal(policyJSON, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", filename, err)
+	}
+
+	policy.principals = make(map[string]*Principal)
+	for i, principal := range policy.Principals {
+		if principal.Name == "" {
+			return nil, fmt.Errorf("principal %d of the policy has no name", i)
+		}
+		if _, ok := policy.principals[principal.Name]; ok {
+			return nil, fmt.Errorf("principal %s is defined more than once", principal.Name)
+		}
+		if len(principal.Identities) == 0 && len(principal.OfflineMSPIDs) == 0 {
+			return nil, fmt.Errorf("principal %s has no identities or offline MSP IDs", principal.Name)
+		}
+		policy.principals[principal.Name] = &policy.Principals[i]
+	}
+
+	return &policy, nil
+}
+
+// Validate checks that every identity and MSP the policy refers to is in the profiles.
+func (policy *Policy) Validate(profiles *Profiles) error {
+	identities := make(map[string]bool)
+	for _, id := range profiles.Identities {
+		identities[id.Name] = true
+	}
+	mspIDs := map[string]bool{"*": true}
+	for _, org := range profiles.Organizations {
+		mspIDs[org.MSPID] = true
+	}
+
+	for _, principal := range policy.Principals {
+		for _, name := range principal.Identities {
+			if !identities[name] {
+				return fmt.Errorf("principal %s refers to unknown identity %s", principal.Name, name)
+			}
+		}
+		for _, mspID := range principal.OfflineMSPIDs {
+			if !mspIDs[mspID] {
+				return fmt.Errorf("principal %s refers to unknown MSP %s", principal.Name, mspID)
+			}
+		}
+	}
+	return nil
+}
+
+// Principal returns a principal of the policy.
+func (policy *Policy) Principal(name string) (*Principal, bool) {
+	principal, ok := policy.principals[name]
+	return principal, ok
+}
+
+// Identity returns the identity a principal signs with: the requested one if the principal may use it,
+// or its first identity if none is requested.
+func (principal *Principal) Identity(requested string) (string, error) {
+	if requested == "" {
+		if len(principal.Identities) == 0 {
+			return "", fmt.Errorf("%s has no identities and may only relay offline-signed transactions", principal.Name)
+		}
+		return principal.Identities[0], nil
+	}
+	for _, name := range principal.Identities {
+		if name == requested {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%s may not use identity %s", principal.Name, requested)
+}
+
+// Allows reports whether a principal may run a function of a chaincode on a channel.
+func (principal *Principal) Allows(channel, chaincode, function string) bool {
+	for _, rule := range principal.Allow {
+		if !matches(rule.Channel, channel) || !matches(rule.Chaincode, chaincode) {
+			continue
+		}
+		for _, allowed := range rule.Functions {
+			if matches(allowed, function) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsOfflineMSP reports whether a principal may relay offline-signed transactions for an MSP.
+func (principal *Principal) AllowsOfflineMSP(mspID string) bool {
+	for _, allowed := range principal.OfflineMSPIDs {
+		if matches(allowed, mspID) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(pattern, value string) bool {
+	return pattern == "*" || pattern == value
+}
@@ -0,0 +1,131 @@
+package web
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicy = `{"principals":[
+	{"name":"org1-portal","identities":["org1-user1","org1-admin"],"allow":[
+		{"channel":"mychannel","chaincode":"basic","functions":["ReadAsset","TransferAsset"]},
+		{"channel":"*","chaincode":"qscc","functions":["*"]}]},
+	{"name":"dashboard","identities":["org2-user1"],"allow":[
+		{"channel":"mychannel","chaincode":"*","functions":["BlockEvents"]}]},
+	{"name":"wallet-relay","offlineMspIds":["Org2MSP"],"allow":[
+		{"channel":"mychannel","chaincode":"basic","functions":["*"]}]}]}`
+
+func TestPrincipalAllows(t *testing.T) {
+	policy := loadTestPolicy(t, testPolicy)
+
+	tests := []struct {
+		principal string
+		channel   string
+		chaincode string
+		function  string
+		want      bool
+	}{
+		{"org1-portal", "mychannel", "basic", "ReadAsset", true},
+		{"org1-portal", "mychannel", "basic", "DeleteAsset", false},
+		{"org1-portal", "otherchannel", "basic", "ReadAsset", false},
+		{"org1-portal", "mychannel", "other", "ReadAsset", false},
+		{"org1-portal", "otherchannel", "qscc", "GetChainInfo", true},
+		{"dashboard", "mychannel", "*", "BlockEvents", true},
+		{"dashboard", "mychannel", "basic", "BlockEvents", true},
+		{"dashboard", "mychannel", "basic", "ChaincodeEvents", false},
+		{"dashboard", "otherchannel", "*", "BlockEvents", false},
+		{"wallet-relay", "mychannel", "basic", "DeleteAsset", true},
+		{"wallet-relay", "mychannel", "qscc", "GetChainInfo", false},
+	}
+	for _, test := range tests {
+		principal, ok := policy.Principal(test.principal)
+		if !ok {
+			t.Fatalf("principal %s is missing", test.principal)
+		}
+		if got := principal.Allows(test.channel, test.chaincode, test.function); got != test.want {
+			t.Errorf("%s.Allows(%q, %q, %q) = %v, want %v", test.principal, test.channel, test.chaincode, test.function, got, test.want)
+		}
+	}
+
+	if _, ok := policy.Principal("unknown"); ok {
+		t.Error("an unknown principal must not be found")
+	}
+}
+
+func TestPrincipalIdentity(t *testing.T) {
+	policy := loadTestPolicy(t, testPolicy)
+	portal, _ := policy.Principal("org1-portal")
+	relay, _ := policy.Principal("wallet-relay")
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		requested string
+		want      string
+		err       string
+	}{
+		{"default", portal, "", "org1-user1", ""},
+		{"requested", portal, "org1-admin", "org1-admin", ""},
+		{"not allowed", portal, "org2-user1", "", "org1-portal may not use identity org2-user1"},
+		{"offline only", relay, "", "", "wallet-relay has no identities and may only relay offline-signed transactions"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.principal.Identity(test.requested)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Fatalf("error = %v, want %q", err, test.err)
+			case got != test.want:
+				t.Fatalf("identity = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if !relay.AllowsOfflineMSP("Org2MSP") || relay.AllowsOfflineMSP("Org1MSP") || portal.AllowsOfflineMSP("Org2MSP") {
+		t.Error("offline MSPs must only be allowed as listed")
+	}
+	if !(&Principal{OfflineMSPIDs: []string{"*"}}).AllowsOfflineMSP("Org1MSP") {
+		t.Error("a wildcard must allow any offline MSP")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		err    string
+	}{
+		{"no name", `{"principals":[{"identities":["org1-user1"]}]}`, "principal 0 of the policy has no name"},
+		{"duplicate", `{"principals":[{"name":"a","identities":["org1-user1"]},{"name":"a","identities":["org1-user1"]}]}`, "principal a is defined more than once"},
+		{"no identities", `{"principals":[{"name":"a"}]}`, "principal a has no identities or offline MSP IDs"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(filename, []byte(test.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPolicy(filename); err == nil || err.Error() != test.err {
+				t.Fatalf("error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	profiles := &Profiles{
+		Organizations: []OrgSetup{{OrgName: "Org1", MSPID: "Org1MSP"}, {OrgName: "Org2", MSPID: "Org2MSP"}},
+		Identities:    []Identity{{Name: "org1-user1"}, {Name: "org1-admin"}, {Name: "org2-user1"}},
+	}
+	if err := loadTestPolicy(t, testPolicy).Validate(profiles); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	profiles.Identities = profiles.Identities[:2]
+	want := "principal dashboard refers to unknown identity org2-user1"
+	if err := loadTestPolicy(t, testPolicy).Validate(profiles); err == nil || err.Error() != want {
+		t.Fatalf("error = %v, want %q", err, want)
+	}
+}
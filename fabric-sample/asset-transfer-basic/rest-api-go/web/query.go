@@ -6,7 +6,7 @@ import (
 )
 
 // Query handles chaincode query requests.
-func (setup OrgSetup) Query(w http.ResponseWriter, r *http.Request) {
+func (server *Server) Query(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Received Query request")
 	queryParams := r.URL.Query()
 	chainCodeName := queryParams.Get("chaincodeid")
@@ -14,11 +14,20 @@ func (setup OrgSetup) Query(w http.ResponseWriter, r *http.Request) {
 	function := queryParams.Get("function")
 	args := r.URL.Query()["args"]
 	fmt.Printf("channel: %s, chaincode: %s, function: %s, args: %s\n", channelID, chainCodeName, function, args)
-	network := setup.Gateway.GetNetwork(channelID)
+	if err := server.authorize(r, channelID, chainCodeName, function); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	gateway, err := server.gateway(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	network := gateway.GetNetwork(channelID)
 	contract := network.GetContract(chainCodeName)
 	evaluateResponse, err := contract.EvaluateTransaction(function, args...)
 	if err != nil {
-		fmt.Fprintf(w, "Error: %s", err)
+		writeGatewayError(w, err)
 		return
 	}
 	fmt.Fprintf(w, "Response: %s", evaluateResponse)
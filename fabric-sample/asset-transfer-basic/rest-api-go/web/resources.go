@@ -0,0 +1,277 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+//go:embed openapi.yaml
+var openAPIDocument []byte
+
+// OpenAPI serves the OpenAPI document of the resource endpoints.
+func (server *Server) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPIDocument)
+}
+
+// Assets handles GET /assets and POST /assets.
+func (server *Server) Assets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		server.listAssets(w, r)
+	case http.MethodPost:
+		server.createAsset(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	}
+}
+
+// Asset handles GET /assets/{id}, POST /assets/{id}/transfer and GET /assets/{id}/history.
+func (server *Server) Asset(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/assets/"), "/")
+	if id == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no asset id in %s", r.URL.Path))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		server.readAsset(w, r, id)
+	case action == "transfer" && r.Method == http.MethodPost:
+		server.transferAsset(w, r, id)
+	case action == "history" && r.Method == http.MethodGet:
+		server.assetHistory(w, r, id)
+	case action == "" || action == "transfer" || action == "history":
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown resource %s", r.URL.Path))
+	}
+}
+
+// Credit handles GET /credits/{id}.
+func (server *Server) Credit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/credits/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown resource %s", r.URL.Path))
+		return
+	}
+
+	var credit Credit
+	if server.evaluate(w, r, &credit, "ReadCredit", id) {
+		writeJSON(w, http.StatusOK, credit)
+	}
+}
+
+func (server *Server) listAssets(w http.ResponseWriter, r *http.Request) {
+	var records []*Asset
+	if !server.evaluate(w, r, &records, "GetAllAssets") {
+		return
+	}
+
+	// the chaincode keeps credit records in the same namespace; they have no owner
+	assets := []*Asset{}
+	for _, asset := range records {
+		if asset.Owner != "" {
+			assets = append(assets, asset)
+		}
+	}
+	writeJSON(w, http.StatusOK, assets)
+}
+
+func (server *Server) readAsset(w http.ResponseWriter, r *http.Request, id string) {
+	var asset Asset
+	if server.evaluate(w, r, &asset, "ReadAsset", id) {
+		writeJSON(w, http.StatusOK, asset)
+	}
+}
+
+func (server *Server) assetHistory(w http.ResponseWriter, r *http.Request, id string) {
+	history := []*Asset{}
+	if server.evaluate(w, r, &history, "GetHistoryForKey", id) {
+		writeJSON(w, http.StatusOK, history)
+	}
+}
+
+func (server *Server) createAsset(w http.ResponseWriter, r *http.Request) {
+	var request CreateAssetRequest
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.ID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("ID is required"))
+		return
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	contract, err := server.contract(r, "CreateAssetFromInspection")
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	if isAsync(r) {
+		server.submitAsync(w, r, contract, "CreateAssetFromInspection", string(requestJSON))
+		return
+	}
+
+	// the asset is taken from the endorsed result: the caller may create assets without being allowed
+	// to read them, and a read right after the commit may not see the new asset on every peer
+	result, commit, err := contract.SubmitAsync("CreateAssetFromInspection", client.WithArguments(string(requestJSON)))
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	status, err := commit.Status()
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	if !status.Successful {
+		writeCommitFailure(w, status)
+		return
+	}
+
+	response := CreateAssetResponse{TransactionID: status.TransactionID}
+	err = json.Unmarshal(result, &response.Asset)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to parse CreateAssetFromInspection result: %w", err))
+		return
+	}
+
+	w.Header().Set("Location", "/assets/"+request.ID)
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (server *Server) transferAsset(w http.ResponseWriter, r *http.Request, id string) {
+	var request TransferRequest
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.NewOwner == "" {
+		writeError(w, http.StatusBadRequest, errors.New("newOwner is required"))
+		return
+	}
+
+	contract, err := server.contract(r, "TransferAsset")
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	if isAsync(r) {
+		server.submitAsync(w, r, contract, "TransferAsset", id, request.NewOwner)
+		return
+	}
+
+	result, commit, err := contract.SubmitAsync("TransferAsset", client.WithArguments(id, request.NewOwner))
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	status, err := commit.Status()
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	if !status.Successful {
+		writeCommitFailure(w, status)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TransferResponse{
+		TransactionID: status.TransactionID,
+		OldOwner:      string(result),
+		NewOwner:      request.NewOwner,
+	})
+}
+
+// contract returns the chaincode the resource endpoints use, connected with the identity of a request,
+// if the principal of the request may run a function of it.
+func (server *Server) contract(r *http.Request, function string) (*client.Contract, error) {
+	if err := server.authorize(r, server.ChannelID, server.ChaincodeName, function); err != nil {
+		return nil, err
+	}
+
+	gateway, err := server.gateway(r)
+	if err != nil {
+		return nil, err
+	}
+	return gateway.GetNetwork(server.ChannelID).GetContract(server.ChaincodeName), nil
+}
+
+// evaluate evaluates a transaction and decodes its JSON result into v. It writes an error response
+// and returns false if the transaction fails.
+func (server *Server) evaluate(w http.ResponseWriter, r *http.Request, v interface{}, function string, args ...string) bool {
+	contract, err := server.contract(r, function)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return false
+	}
+
+	result, err := contract.EvaluateTransaction(function, args...)
+	if err != nil {
+		writeGatewayError(w, err)
+		return false
+	}
+
+	// the contract API returns an empty result for a nil slice
+	if len(result) == 0 {
+		return true
+	}
+
+	err = json.Unmarshal(result, v)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to parse %s result: %w", function, err))
+		return false
+	}
+
+	return true
+}
+
+// decodeBody decodes a JSON request body into v, rejecting unknown fields. It writes an error
+// response and returns false if the body is invalid.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == io.EOF {
+		writeError(w, http.StatusBadRequest, errors.New("the request body is empty"))
+		return false
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateAsset(t *testing.T) {
+	// the principal may create assets but not read them, so the response must not need a read
+	fake := &fakeGateway{
+		evaluateErr: peerError(t, codes.PermissionDenied, "access denied"),
+		result:      []byte(`{"ID":"asset1","Owner":"org1-user1","Category":"A"}`),
+	}
+	endpoint := "localhost:7051"
+	server := &Server{
+		ChannelID:      "mychannel",
+		ChaincodeName:  "basic",
+		Authenticators: []Authenticator{staticAuthenticator("portal")},
+		Policy: loadTestPolicy(t, `{"principals":[{"name":"portal","identities":["org1-user1"],
+			"allow":[{"channel":"mychannel","chaincode":"basic","functions":["CreateAssetFromInspection"]}]}]}`),
+		Pool: &Pool{
+			orgs:       map[string]OrgSetup{"Org1": {OrgName: "Org1", MSPID: "Org1MSP", Peers: []PeerSetup{{Endpoint: endpoint}}}},
+			identities: map[string]Identity{"org1-user1": {Name: "org1-user1", OrgName: "Org1"}},
+			peers:      map[string]*peerConnection{endpoint: {}},
+			gateways:   map[gatewayKey]*client.Gateway{{"org1-user1", endpoint}: connectFake(t, fake)},
+		},
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/assets", strings.NewReader(`{"ID":"asset1","AcceptanceSampling":"3"}`))
+	w := httptest.NewRecorder()
+	server.authenticated(server.Assets)(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if location := w.Header().Get("Location"); location != "/assets/asset1" {
+		t.Errorf("Location = %q, want /assets/asset1", location)
+	}
+	var response CreateAssetResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.TransactionID == "" {
+		t.Error("the response must have the transaction ID")
+	}
+	if response.Asset.ID != "asset1" || response.Asset.Category != "A" {
+		t.Errorf("asset = %+v, want the submit result", response.Asset)
+	}
+}
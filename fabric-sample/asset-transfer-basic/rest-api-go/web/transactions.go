@@ -0,0 +1,292 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Transaction states reported by GET /transactions/{txid}.
+const (
+	TransactionPending = "PENDING"
+	TransactionValid   = "VALID"
+	TransactionInvalid = "INVALID"
+	TransactionUnknown = "UNKNOWN"
+)
+
+// transactionRetention is how long a transaction's status is kept after it was submitted.
+const transactionRetention = time.Hour
+
+// callbackAttempts is how many times a callback is posted before it is given up.
+const callbackAttempts = 3
+
+// TransactionStatus is the commit status of a transaction submitted asynchronously.
+type TransactionStatus struct {
+	TransactionID  string     `json:"transactionId"`
+	Status         string     `json:"status"`
+	ValidationCode string     `json:"validationCode,omitempty"`
+	BlockNumber    uint64     `json:"blockNumber,omitempty"`
+	Result         string     `json:"result,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	SubmittedAt    time.Time  `json:"submittedAt"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	principal      string
+	callback       string
+}
+
+// Tracker follows the commit status of transactions submitted asynchronously.
+type Tracker struct {
+	mu           sync.Mutex
+	transactions map[string]*TransactionStatus
+	allowedHosts []string
+	client       *http.Client
+	pending      sync.WaitGroup
+}
+
+// NewTracker creates an empty transaction tracker that posts callbacks to the allowed hosts only.
+func NewTracker(callbacks CallbackConfig) *Tracker {
+	// the address is checked when connecting, after DNS resolution, so that an allowed name cannot
+	// resolve to an internal address; callbacks are not proxied and redirects are not followed
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: checkCallbackAddress}
+	return &Tracker{
+		transactions: make(map[string]*TransactionStatus),
+		allowedHosts: callbacks.AllowedHosts,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Track records a submitted transaction as pending and waits for its commit status in the background.
+// When the status is known it is posted to the callback URL, if one is given.
+func (tracker *Tracker) Track(principal string, result []byte, commit *client.Commit, callback string) TransactionStatus {
+	transaction := &TransactionStatus{
+		TransactionID: commit.TransactionID(),
+		Status:        TransactionPending,
+		Result:        string(result),
+		SubmittedAt:   time.Now().UTC(),
+		principal:     principal,
+		callback:      callback,
+	}
+
+	tracker.mu.Lock()
+	tracker.prune()
+	tracker.transactions[transaction.TransactionID] = transaction
+	pending := *transaction
+	tracker.mu.Unlock()
+
+	tracker.pending.Add(1)
+	go tracker.wait(transaction, commit)
+
+	return pending
+}
+
+// Wait waits until the status of every tracked transaction is known and its callback posted, or
+// until the context is done.
+func (tracker *Tracker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		tracker.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for pending transactions: %w", ctx.Err())
+	}
+}
+
+// Get returns the status of a transaction submitted by a principal.
+func (tracker *Tracker) Get(principal, transactionID string) (TransactionStatus, bool) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	transaction, ok := tracker.transactions[transactionID]
+	if !ok || transaction.principal != principal {
+		return TransactionStatus{}, false
+	}
+	return *transaction, true
+}
+
+func (tracker *Tracker) wait(transaction *TransactionStatus, commit *client.Commit) {
+	defer tracker.pending.Done()
+
+	commitStatus, err := commit.Status()
+
+	tracker.mu.Lock()
+	switch {
+	case err != nil:
+		transaction.Status = TransactionUnknown
+		transaction.Error = err.Error()
+	case commitStatus.Successful:
+		transaction.Status = TransactionValid
+	default:
+		transaction.Status = TransactionInvalid
+	}
+	if err == nil {
+		transaction.ValidationCode = commitStatus.Code.String()
+		transaction.BlockNumber = commitStatus.BlockNumber
+	}
+	completedAt := time.Now().UTC()
+	transaction.CompletedAt = &completedAt
+	completed := *transaction
+	tracker.mu.Unlock()
+
+	if completed.callback != "" {
+		tracker.notify(completed)
+	}
+}
+
+// notify posts the final status of a transaction to its callback URL.
+func (tracker *Tracker) notify(transaction TransactionStatus) {
+	body, err := json.Marshal(transaction)
+	if err != nil {
+		log.Printf("Failed to encode callback for transaction %s: %v\n", transaction.TransactionID, err)
+		return
+	}
+
+	for attempt := 1; attempt <= callbackAttempts; attempt++ {
+		err = tracker.post(transaction.callback, body)
+		if err == nil {
+			return
+		}
+		if attempt < callbackAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	log.Printf("Failed to post callback for transaction %s to %s: %v\n", transaction.TransactionID, transaction.callback, err)
+}
+
+func (tracker *Tracker) post(callback string, body []byte) error {
+	response, err := tracker.client.Post(callback, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("callback returned %s", response.Status)
+	}
+	return nil
+}
+
+// prune drops transactions older than the retention period. The caller holds the lock.
+func (tracker *Tracker) prune() {
+	cutoff := time.Now().Add(-transactionRetention)
+	for id, transaction := range tracker.transactions {
+		if transaction.SubmittedAt.Before(cutoff) {
+			delete(tracker.transactions, id)
+		}
+	}
+}
+
+// Transaction handles GET /transactions/{txid}.
+func (server *Server) Transaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	transactionID := strings.TrimPrefix(r.URL.Path, "/transactions/")
+	transaction, ok := server.Transactions.Get(principal(r).Name, transactionID)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("the transaction %s is not tracked", transactionID))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transaction)
+}
+
+// submitAsync submits a transaction of the server's chaincode and responds with 202 Accepted once the orderer accepts it. The
+// commit status is tracked in the background.
+func (server *Server) submitAsync(w http.ResponseWriter, r *http.Request, contract *client.Contract, function string, args ...string) {
+	callback, err := server.Transactions.callbackURL(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, commit, err := contract.SubmitAsync(function, client.WithArguments(args...))
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	transaction := server.Transactions.Track(principal(r).Name, result, commit, callback)
+	w.Header().Set("Location", "/transactions/"+transaction.TransactionID)
+	writeJSON(w, http.StatusAccepted, transaction)
+}
+
+// isAsync reports whether a request asks to be answered before its transaction commits.
+func isAsync(r *http.Request) bool {
+	return r.FormValue("async") == "true"
+}
+
+// callbackURL returns the callback URL of a request, if it has one. The URL must name an allowed host.
+func (tracker *Tracker) callbackURL(r *http.Request) (string, error) {
+	callback := r.FormValue("callback")
+	if callback == "" {
+		return "", nil
+	}
+
+	parsed, err := url.Parse(callback)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.User != nil {
+		return "", errors.New("callback must be an absolute http or https URL")
+	}
+	if !tracker.allowedHost(parsed.Hostname()) {
+		return "", fmt.Errorf("callbacks to %s are not allowed", parsed.Hostname())
+	}
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil && !isPublicAddress(ip) {
+		return "", fmt.Errorf("callbacks to %s are not allowed", parsed.Hostname())
+	}
+	return callback, nil
+}
+
+// allowedHost reports whether callbacks may be posted to a host.
+func (tracker *Tracker) allowedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range tracker.allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCallbackAddress refuses to connect a callback to an address that is not public.
+func checkCallbackAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+		return fmt.Errorf("callbacks to %s are not allowed", host)
+	}
+	return nil
+}
+
+// isPublicAddress reports whether an IP address is routable on the internet, rather than a
+// loopback, private, link-local, multicast or unspecified address.
+func isPublicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
@@ -0,0 +1,127 @@
+package web
+
+import "time"
+
+// Asset is an asset as stored by the asset-transfer-basic chaincode. Checkpoint results are 1 for a
+// pass, 0 for a failure, -1 when the checkpoint does not apply and -2 for a stage still pending.
+type Asset struct {
+	ID                      string               `json:"ID"`
+	Owner                   string               `json:"Owner"`
+	Category                string               `json:"Category"`
+	AcceptanceSampling      int                  `json:"AcceptanceSampling"`
+	ManufacturingEquipment  int                  `json:"ManufacturingEquipment"`
+	TransportationEquipment int                  `json:"TransportationEquipment"`
+	InventoryManagement     int                  `json:"InventoryManagement"`
+	SaveEquipment           int                  `json:"SaveEquipment"`
+	A01                     int                  `json:"A01"`
+	A02                     int                  `json:"A02"`
+	A03                     int                  `json:"A03"`
+	A04                     int                  `json:"A04"`
+	B01                     int                  `json:"B01"`
+	C01                     int                  `json:"C01"`
+	Source                  string               `json:"Source"`
+	CheckedAt               map[string]time.Time `json:"CheckedAt"`
+	Recall                  *AssetRecall         `json:"Recall,omitempty"`
+	TimeStamp               time.Time            `json:"TimeStamp"`
+	Sender                  string               `json:"Sender"`
+	Function                string               `json:"Function"`
+}
+
+// AssetRecall marks an asset affected by a recall.
+type AssetRecall struct {
+	ID           string `json:"ID"`
+	Reason       string `json:"Reason"`
+	Acknowledged bool   `json:"Acknowledged"`
+}
+
+// Credit is a participant's credit record. Callers that may only see the grade get the other fields empty.
+type Credit struct {
+	ID          string  `json:"ID"`
+	Transaction float32 `json:"Transaction"`
+	Score       float32 `json:"Score"`
+	FinalScore  float32 `json:"FinalScore"`
+	Grade       string  `json:"Grade,omitempty"`
+}
+
+// CreateAssetRequest is the body of POST /assets. Every checkpoint takes "pass", "fail", "not_applicable"
+// or "pending".
+type CreateAssetRequest struct {
+	ID                      string `json:"ID"`
+	Category                string `json:"Category,omitempty"`
+	AcceptanceSampling      string `json:"AcceptanceSampling"`
+	ManufacturingEquipment  string `json:"ManufacturingEquipment"`
+	TransportationEquipment string `json:"TransportationEquipment"`
+	InventoryManagement     string `json:"InventoryManagement"`
+	SaveEquipment           string `json:"SaveEquipment"`
+	A01                     string `json:"A01"`
+	A02                     string `json:"A02"`
+	A03                     string `json:"A03"`
+	A04                     string `json:"A04"`
+	B01                     string `json:"B01"`
+	C01                     string `json:"C01"`
+}
+
+// CreateAssetResponse is the result of creating an asset: the asset as committed by the transaction.
+type CreateAssetResponse struct {
+	TransactionID string `json:"transactionId"`
+	Asset         Asset  `json:"asset"`
+}
+
+// TransferRequest is the body of POST /assets/{id}/transfer.
+type TransferRequest struct {
+	NewOwner string `json:"newOwner"`
+}
+
+// TransferResponse is the result of a transfer.
+type TransferResponse struct {
+	TransactionID string `json:"transactionId"`
+	OldOwner      string `json:"oldOwner"`
+	NewOwner      string `json:"newOwner"`
+}
+
+// OfflineProposalRequest is the body of POST /offline/proposals. Certificate is the PEM certificate
+// of the end user who signs the transaction; Channel and Chaincode default to the server's.
+type OfflineProposalRequest struct {
+	MSPID       string   `json:"mspId"`
+	Certificate string   `json:"certificate"`
+	Channel     string   `json:"channel,omitempty"`
+	Chaincode   string   `json:"chaincode,omitempty"`
+	Function    string   `json:"function"`
+	Args        []string `json:"args"`
+}
+
+// OfflineSigningStep is a message the end user signs: Digest is the SHA-256 digest of Bytes. The
+// signature is sent back with Bytes to the next step.
+type OfflineSigningStep struct {
+	TransactionID string `json:"transactionId"`
+	Result        string `json:"result,omitempty"`
+	Bytes         []byte `json:"bytes"`
+	Digest        []byte `json:"digest"`
+}
+
+// OfflineSignedRequest returns a message with the end user's ECDSA signature of its digest.
+type OfflineSignedRequest struct {
+	Bytes     []byte `json:"bytes"`
+	Signature []byte `json:"signature"`
+}
+
+// ErrorResponse is the body of every error response. Kind is the type of failure: RequestError for
+// a request the server rejects, EvaluateError, EndorseError, SubmitError, CommitStatusError or
+// CommitError for a failure at that step of a transaction.
+type ErrorResponse struct {
+	Kind             string        `json:"kind"`
+	Error            string        `json:"error"`
+	TransactionID    string        `json:"transactionId,omitempty"`
+	GRPCStatus       string        `json:"grpcStatus,omitempty"`
+	ChaincodeStatus  int           `json:"chaincodeStatus,omitempty"`
+	ChaincodeMessage string        `json:"chaincodeMessage,omitempty"`
+	ValidationCode   string        `json:"validationCode,omitempty"`
+	Details          []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail is the error a peer or orderer node returned to the Gateway.
+type ErrorDetail struct {
+	Address string `json:"address"`
+	MSPID   string `json:"mspId"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,287 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// telemetry-replay reads sensor readings from a CSV file, signs them in batches with the device key
+// and submits them to the SubmitTelemetry transaction of the asset-transfer-basic chaincode.
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	mspID        = "Org1MSP"
+	cryptoPath   = "../../test-network/organizations/peerOrganizations/org1.example.com"
+	certPath     = cryptoPath + "/users/Admin@org1.example.com/msp/signcerts/cert.pem"
+	keyPath      = cryptoPath + "/users/Admin@org1.example.com/msp/keystore/"
+	tlsCertPath  = cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt"
+	peerEndpoint = "localhost:7051"
+	gatewayPeer  = "peer0.org1.example.com"
+)
+
+// Reading and TelemetryBatch mirror the payload expected by SubmitTelemetry.
+type Reading struct {
+	Metric    string    `json:"Metric"`
+	Value     float64   `json:"Value"`
+	TimeStamp time.Time `json:"TimeStamp"`
+}
+
+type TelemetryBatch struct {
+	AssetID  string    `json:"AssetID"`
+	Sequence int       `json:"Sequence"`
+	Readings []Reading `json:"Readings"`
+}
+
+func main() {
+	csvPath := flag.String("csv", "readings.csv", "CSV file with asset,metric,value,timestamp rows")
+	deviceID := flag.String("device", "", "registered device id")
+	deviceKey := flag.String("key", "", "PEM encoded ECDSA private key of the device")
+	sequence := flag.Int("sequence", 1, "sequence number of the first batch")
+	batchSize := flag.Int("batch", 10, "maximum number of readings per batch")
+	dryRun := flag.Bool("dry-run", false, "print the signed batches instead of submitting them")
+	flag.Parse()
+
+	if *deviceID == "" || *deviceKey == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	batches, err := readBatches(*csvPath, *sequence, *batchSize)
+	if err != nil {
+		panic(err)
+	}
+
+	signer, err := loadDeviceKey(*deviceKey)
+	if err != nil {
+		panic(err)
+	}
+
+	var contract *client.Contract
+	if !*dryRun {
+		clientConnection := newGrpcConnection()
+		defer clientConnection.Close()
+
+		gw, err := client.Connect(
+			newIdentity(),
+			client.WithSign(newSign()),
+			client.WithClientConnection(clientConnection),
+			client.WithEvaluateTimeout(5*time.Second),
+			client.WithEndorseTimeout(15*time.Second),
+			client.WithSubmitTimeout(5*time.Second),
+			client.WithCommitStatusTimeout(1*time.Minute),
+		)
+		if err != nil {
+			panic(err)
+		}
+		defer gw.Close()
+
+		chaincodeName := "basic"
+		if ccname := os.Getenv("CHAINCODE_NAME"); ccname != "" {
+			chaincodeName = ccname
+		}
+
+		channelName := "mychannel"
+		if cname := os.Getenv("CHANNEL_NAME"); cname != "" {
+			channelName = cname
+		}
+
+		contract = gw.GetNetwork(channelName).GetContract(chaincodeName)
+	}
+
+	for _, batch := range batches {
+		payload, signature, err := signBatch(signer, batch)
+		if err != nil {
+			panic(err)
+		}
+
+		if *dryRun {
+			fmt.Printf("%s %d %s %s\n", batch.AssetID, batch.Sequence, payload, signature)
+			continue
+		}
+
+		fmt.Printf("\n--> Submit Transaction: SubmitTelemetry, batch %d of asset %s with %d readings\n", batch.Sequence, batch.AssetID, len(batch.Readings))
+		_, err = contract.SubmitTransaction("SubmitTelemetry", *deviceID, payload, signature)
+		if err != nil {
+			panic(fmt.Errorf("failed to submit transaction: %w", err))
+		}
+		fmt.Printf("*** Transaction committed successfully\n")
+	}
+}
+
+// readBatches groups consecutive readings of the same asset into batches of at most batchSize
+// readings, numbered from the given sequence.
+func readBatches(filename string, sequence int, batchSize int) ([]TelemetryBatch, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 4
+	reader.Comment = '#'
+
+	var batches []TelemetryBatch
+	var current *TelemetryBatch
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && record[0] == "asset" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", line, record[2])
+		}
+		timestamp, err := time.Parse(time.RFC3339, record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp %q", line, record[3])
+		}
+
+		if current == nil || current.AssetID != record[0] || len(current.Readings) == batchSize {
+			batches = append(batches, TelemetryBatch{AssetID: record[0], Sequence: sequence})
+			current = &batches[len(batches)-1]
+			sequence++
+		}
+		current.Readings = append(current.Readings, Reading{Metric: record[1], Value: value, TimeStamp: timestamp})
+	}
+
+	return batches, nil
+}
+
+// signBatch returns the base64 encoded payload of a batch and its ASN.1 ECDSA signature over the
+// SHA-256 digest of the payload.
+func signBatch(key *ecdsa.PrivateKey, batch TelemetryBatch) (string, string, error) {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return "", "", err
+	}
+
+	digest := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(payload), base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func loadDeviceKey(filename string) (*ecdsa.PrivateKey, error) {
+	keyPEM, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device key file: %w", err)
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode device key PEM")
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the device key must be an ECDSA key")
+	}
+
+	return ecKey, nil
+}
+
+// newGrpcConnection creates a gRPC connection to the Gateway server.
+func newGrpcConnection() *grpc.ClientConn {
+	certificate, err := loadCertificate(tlsCertPath)
+	if err != nil {
+		panic(err)
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, gatewayPeer)
+
+	connection, err := grpc.Dial(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+	}
+
+	return connection
+}
+
+// newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
+func newIdentity() *identity.X509Identity {
+	certificate, err := loadCertificate(certPath)
+	if err != nil {
+		panic(err)
+	}
+
+	id, err := identity.NewX509Identity(mspID, certificate)
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+func loadCertificate(filename string) (*x509.Certificate, error) {
+	certificatePEM, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	return identity.CertificateFromPEM(certificatePEM)
+}
+
+// newSign creates a function that generates a digital signature from a message digest using a private key.
+func newSign() identity.Sign {
+	files, err := os.ReadDir(keyPath)
+	if err != nil {
+		panic(fmt.Errorf("failed to read private key directory: %w", err))
+	}
+	privateKeyPEM, err := os.ReadFile(path.Join(keyPath, files[0].Name()))
+	if err != nil {
+		panic(fmt.Errorf("failed to read private key file: %w", err))
+	}
+
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		panic(err)
+	}
+
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return sign
+}